@@ -0,0 +1,628 @@
+package datahelper
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/eaglebush/datatable"
+)
+
+// SelectBuilder - builds a SELECT query for the connection of a DataHelper
+type SelectBuilder struct {
+	dh       *DataHelper
+	distinct bool
+	columns  []string
+	from     string
+	joins    []queryPart
+	where    []queryPart
+	groupBy  []string
+	having   []queryPart
+	orderBy  []string
+	limit    int64
+	offset   int64
+	err      error
+}
+
+// InsertBuilder - builds an INSERT statement for the connection of a DataHelper
+type InsertBuilder struct {
+	dh      *DataHelper
+	table   string
+	columns []string
+	rows    [][]interface{}
+	err     error
+}
+
+// UpdateBuilder - builds an UPDATE statement for the connection of a DataHelper
+type UpdateBuilder struct {
+	dh    *DataHelper
+	table string
+	sets  []queryPart
+	where []queryPart
+	err   error
+}
+
+// DeleteBuilder - builds a DELETE statement for the connection of a DataHelper
+type DeleteBuilder struct {
+	dh    *DataHelper
+	table string
+	where []queryPart
+	err   error
+}
+
+// queryPart - a part of a query with its ? parameters
+type queryPart struct {
+	SQL  string
+	Args []interface{}
+}
+
+// Select - starts a SELECT query. Column names that are reserved words are quoted.
+//
+// Queries are written with ? parameters and {table} placeholders. ToSQL returns them
+// rewritten for the connection.
+func (dh *DataHelper) Select(columns ...string) *SelectBuilder {
+	return &SelectBuilder{
+		dh:      dh,
+		columns: columns,
+	}
+}
+
+// Distinct - selects distinct rows
+func (b *SelectBuilder) Distinct() *SelectBuilder {
+	b.distinct = true
+	return b
+}
+
+// From - sets the table to select from. It could have an alias, like "{USERACCOUNT} ua".
+func (b *SelectBuilder) From(table string) *SelectBuilder {
+	b.from = table
+	return b
+}
+
+// Join - adds an INNER JOIN
+func (b *SelectBuilder) Join(table, on string, args ...interface{}) *SelectBuilder {
+	return b.join(`INNER JOIN`, table, on, args)
+}
+
+// LeftJoin - adds a LEFT JOIN
+func (b *SelectBuilder) LeftJoin(table, on string, args ...interface{}) *SelectBuilder {
+	return b.join(`LEFT JOIN`, table, on, args)
+}
+
+func (b *SelectBuilder) join(kind, table, on string, args []interface{}) *SelectBuilder {
+	b.joins = append(b.joins, queryPart{
+		SQL:  kind + ` ` + b.dh.quoteName(table) + ` ON ` + on,
+		Args: args,
+	})
+	return b
+}
+
+// Where - adds a condition. Conditions are joined with AND.
+// A slice argument is expanded to a list, so that "UserKey IN (?)" could take []int{1, 2}.
+// An empty slice is an error, returned by Err and GetData.
+func (b *SelectBuilder) Where(condition string, args ...interface{}) *SelectBuilder {
+	qp, err := b.dh.newQueryPart(condition, args)
+	b.where = append(b.where, qp)
+	b.err = firstError(b.err, err)
+	return b
+}
+
+// GroupBy - sets the grouping columns
+func (b *SelectBuilder) GroupBy(columns ...string) *SelectBuilder {
+	b.groupBy = append(b.groupBy, columns...)
+	return b
+}
+
+// Having - adds a condition on the groups. Conditions are joined with AND.
+func (b *SelectBuilder) Having(condition string, args ...interface{}) *SelectBuilder {
+	qp, err := b.dh.newQueryPart(condition, args)
+	b.having = append(b.having, qp)
+	b.err = firstError(b.err, err)
+	return b
+}
+
+// OrderBy - sets the ordering, such as "UserName" or "DateLastLoggedIn DESC"
+func (b *SelectBuilder) OrderBy(columns ...string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, columns...)
+	return b
+}
+
+// Limit - limits the number of rows with the row limiting of the connection
+func (b *SelectBuilder) Limit(n int64) *SelectBuilder {
+	b.limit = n
+	return b
+}
+
+// Offset - skips a number of rows. It only applies with a Limit.
+func (b *SelectBuilder) Offset(n int64) *SelectBuilder {
+	b.offset = n
+	return b
+}
+
+// Err - returns the first error found while building the query
+func (b *SelectBuilder) Err() error {
+	return b.err
+}
+
+// Query - returns the query with ? parameters and {table} placeholders, as accepted by GetData
+func (b *SelectBuilder) Query() (string, []interface{}) {
+
+	var (
+		sb   strings.Builder
+		args []interface{}
+	)
+
+	rl := b.dh.RowLimitInfo
+	paged := b.limit > 0 && b.offset > 0
+
+	sb.WriteString(`SELECT `)
+	if b.distinct {
+		sb.WriteString(`DISTINCT `)
+	}
+
+	if b.limit > 0 && !paged && rl.Placement == RowLimitingFront {
+		sb.WriteString(rl.Keyword + ` ` + strconv.FormatInt(b.limit, 10) + ` `)
+	}
+
+	if len(b.columns) == 0 {
+		sb.WriteString(`*`)
+	}
+	for i, c := range b.columns {
+		if i > 0 {
+			sb.WriteString(`, `)
+		}
+		sb.WriteString(b.dh.quoteName(c))
+	}
+
+	if b.from != "" {
+		sb.WriteString(` FROM ` + b.dh.quoteName(b.from))
+	}
+
+	for _, j := range b.joins {
+		sb.WriteString(` ` + j.SQL)
+		args = append(args, j.Args...)
+	}
+
+	args = writeConditions(&sb, ` WHERE `, b.where, args)
+
+	if len(b.groupBy) > 0 {
+		sb.WriteString(` GROUP BY ` + b.dh.quoteNames(b.groupBy))
+	}
+
+	args = writeConditions(&sb, ` HAVING `, b.having, args)
+
+	if len(b.orderBy) > 0 {
+		sb.WriteString(` ORDER BY ` + b.dh.quoteNames(b.orderBy))
+	}
+
+	switch {
+	case paged:
+		// SQL Server requires an ORDER BY for OFFSET
+		if len(b.orderBy) == 0 && rl.Placement == RowLimitingFront {
+			sb.WriteString(` ORDER BY (SELECT NULL)`)
+		}
+		sb.WriteString(` ` + b.dh.dialect().LimitOffset(b.limit, b.offset))
+	case b.limit > 0 && rl.Placement == RowLimitingRear:
+		sb.WriteString(` ` + rl.Keyword + ` ` + strconv.FormatInt(b.limit, 10))
+	}
+
+	return sb.String(), args
+}
+
+// ToSQL - returns the query with the parameter placeholders and schema of the connection
+func (b *SelectBuilder) ToSQL() (string, []interface{}) {
+	q, args := b.Query()
+	return b.dh.rewriteQuery(q), args
+}
+
+// GetData - runs the query and returns the result in a tabular form
+func (b *SelectBuilder) GetData() (*datatable.DataTable, error) {
+	return b.GetDataContext(context.Background())
+}
+
+// GetDataContext - runs the query with a context and returns the result in a tabular form
+func (b *SelectBuilder) GetDataContext(ctx context.Context) (*datatable.DataTable, error) {
+	if b.err != nil {
+		return datatable.NewDataTable("data"), b.err
+	}
+	q, args := b.Query()
+	return b.dh.GetDataContext(ctx, q, args...)
+}
+
+// InsertInto - starts an INSERT statement
+func (dh *DataHelper) InsertInto(table string) *InsertBuilder {
+	return &InsertBuilder{
+		dh:    dh,
+		table: table,
+	}
+}
+
+// Columns - sets the columns to insert
+func (b *InsertBuilder) Columns(columns ...string) *InsertBuilder {
+	b.columns = columns
+	return b
+}
+
+// Values - adds a row of values in the order of the columns set before it. More than one row
+// could be added. A row with a different number of values than columns is an error, returned by Err and Exec.
+func (b *InsertBuilder) Values(values ...interface{}) *InsertBuilder {
+	if len(values) != len(b.columns) {
+		b.err = firstError(b.err, fmt.Errorf("%w: %d values for %d columns", ErrValueCount, len(values), len(b.columns)))
+	}
+	b.rows = append(b.rows, values)
+	return b
+}
+
+// Set - sets a column value of a single row insert
+func (b *InsertBuilder) Set(column string, value interface{}) *InsertBuilder {
+	if len(b.rows) == 0 {
+		b.rows = append(b.rows, nil)
+	}
+	b.columns = append(b.columns, column)
+	b.rows[0] = append(b.rows[0], value)
+	return b
+}
+
+// Err - returns the first error found while building the statement
+func (b *InsertBuilder) Err() error {
+	return b.err
+}
+
+// Query - returns the statement with ? parameters and {table} placeholders, as accepted by Exec
+func (b *InsertBuilder) Query() (string, []interface{}) {
+
+	var (
+		sb   strings.Builder
+		args []interface{}
+	)
+
+	sb.WriteString(`INSERT INTO ` + b.dh.quoteName(b.table) + ` (` + b.dh.quoteNames(b.columns) + `) VALUES `)
+
+	row := `(` + markers(len(b.columns)) + `)`
+	for i, r := range b.rows {
+		if i > 0 {
+			sb.WriteString(`, `)
+		}
+		sb.WriteString(row)
+		args = append(args, r...)
+	}
+
+	return sb.String(), args
+}
+
+// ToSQL - returns the statement with the parameter placeholders and schema of the connection
+func (b *InsertBuilder) ToSQL() (string, []interface{}) {
+	q, args := b.Query()
+	return b.dh.rewriteQuery(q), args
+}
+
+// Exec - runs the statement
+func (b *InsertBuilder) Exec() (sql.Result, error) {
+	return b.ExecContext(context.Background())
+}
+
+// ExecContext - runs the statement with a context
+func (b *InsertBuilder) ExecContext(ctx context.Context) (sql.Result, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	q, args := b.Query()
+	return b.dh.ExecContext(ctx, q, args...)
+}
+
+// UpdateTable - starts an UPDATE statement
+func (dh *DataHelper) UpdateTable(table string) *UpdateBuilder {
+	return &UpdateBuilder{
+		dh:    dh,
+		table: table,
+	}
+}
+
+// Set - sets a column to a value
+func (b *UpdateBuilder) Set(column string, value interface{}) *UpdateBuilder {
+	b.sets = append(b.sets, queryPart{
+		SQL:  b.dh.quoteName(column) + ` = ?`,
+		Args: []interface{}{value},
+	})
+	return b
+}
+
+// SetExpr - sets a column to an expression, such as "SequenceNo + ?"
+func (b *UpdateBuilder) SetExpr(column, expr string, args ...interface{}) *UpdateBuilder {
+	b.sets = append(b.sets, queryPart{
+		SQL:  b.dh.quoteName(column) + ` = ` + expr,
+		Args: args,
+	})
+	return b
+}
+
+// Where - adds a condition. Conditions are joined with AND. An empty slice argument is an error, returned by Err and Exec.
+func (b *UpdateBuilder) Where(condition string, args ...interface{}) *UpdateBuilder {
+	qp, err := b.dh.newQueryPart(condition, args)
+	b.where = append(b.where, qp)
+	b.err = firstError(b.err, err)
+	return b
+}
+
+// Err - returns the first error found while building the statement
+func (b *UpdateBuilder) Err() error {
+	return b.err
+}
+
+// Query - returns the statement with ? parameters and {table} placeholders, as accepted by Exec
+func (b *UpdateBuilder) Query() (string, []interface{}) {
+
+	var (
+		sb   strings.Builder
+		args []interface{}
+	)
+
+	sb.WriteString(`UPDATE ` + b.dh.quoteName(b.table) + ` SET `)
+	for i, s := range b.sets {
+		if i > 0 {
+			sb.WriteString(`, `)
+		}
+		sb.WriteString(s.SQL)
+		args = append(args, s.Args...)
+	}
+
+	args = writeConditions(&sb, ` WHERE `, b.where, args)
+
+	return sb.String(), args
+}
+
+// ToSQL - returns the statement with the parameter placeholders and schema of the connection
+func (b *UpdateBuilder) ToSQL() (string, []interface{}) {
+	q, args := b.Query()
+	return b.dh.rewriteQuery(q), args
+}
+
+// Exec - runs the statement
+func (b *UpdateBuilder) Exec() (sql.Result, error) {
+	return b.ExecContext(context.Background())
+}
+
+// ExecContext - runs the statement with a context
+func (b *UpdateBuilder) ExecContext(ctx context.Context) (sql.Result, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	q, args := b.Query()
+	return b.dh.ExecContext(ctx, q, args...)
+}
+
+// DeleteFrom - starts a DELETE statement
+func (dh *DataHelper) DeleteFrom(table string) *DeleteBuilder {
+	return &DeleteBuilder{
+		dh:    dh,
+		table: table,
+	}
+}
+
+// Where - adds a condition. Conditions are joined with AND. An empty slice argument is an error, returned by Err and Exec.
+func (b *DeleteBuilder) Where(condition string, args ...interface{}) *DeleteBuilder {
+	qp, err := b.dh.newQueryPart(condition, args)
+	b.where = append(b.where, qp)
+	b.err = firstError(b.err, err)
+	return b
+}
+
+// Err - returns the first error found while building the statement
+func (b *DeleteBuilder) Err() error {
+	return b.err
+}
+
+// Query - returns the statement with ? parameters and {table} placeholders, as accepted by Exec
+func (b *DeleteBuilder) Query() (string, []interface{}) {
+
+	var sb strings.Builder
+
+	sb.WriteString(`DELETE FROM ` + b.dh.quoteName(b.table))
+	args := writeConditions(&sb, ` WHERE `, b.where, nil)
+
+	return sb.String(), args
+}
+
+// ToSQL - returns the statement with the parameter placeholders and schema of the connection
+func (b *DeleteBuilder) ToSQL() (string, []interface{}) {
+	q, args := b.Query()
+	return b.dh.rewriteQuery(q), args
+}
+
+// Exec - runs the statement
+func (b *DeleteBuilder) Exec() (sql.Result, error) {
+	return b.ExecContext(context.Background())
+}
+
+// ExecContext - runs the statement with a context
+func (b *DeleteBuilder) ExecContext(ctx context.Context) (sql.Result, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	q, args := b.Query()
+	return b.dh.ExecContext(ctx, q, args...)
+}
+
+// rewriteQuery replaces the parameter markers and {table} placeholders for the connection
+func (dh *DataHelper) rewriteQuery(query string) string {
+	query = dh.replaceQueryParamMarker(query)
+	return replaceCustomPlaceHolder(query, dh.CurrentDatabaseInfo.Schema)
+}
+
+// newQueryPart creates a condition, expanding slice arguments into lists of markers.
+// An empty slice is an error, since an empty list like IN () is not valid SQL.
+func (dh *DataHelper) newQueryPart(condition string, args []interface{}) (queryPart, error) {
+
+	expand := false
+	for _, a := range args {
+		if isListArg(a) {
+			expand = true
+			break
+		}
+	}
+
+	if !expand {
+		return queryPart{SQL: condition, Args: args}, nil
+	}
+
+	var (
+		sb    strings.Builder
+		eargs []interface{}
+		n     int
+	)
+
+	for _, t := range tokenizeSQL(condition, dh.syntax()) {
+		if t.Kind != tokParam || n >= len(args) {
+			if t.Kind == tokPlaceholder {
+				t.Text = `{` + t.Text + `}`
+			} else if t.Kind == tokNamedParam {
+				t.Text = `:` + t.Text
+			}
+			sb.WriteString(t.Text)
+			continue
+		}
+
+		a := args[n]
+		n++
+
+		if !isListArg(a) {
+			sb.WriteString(`?`)
+			eargs = append(eargs, a)
+			continue
+		}
+
+		rv := reflect.ValueOf(a)
+		if rv.Len() == 0 {
+			return queryPart{SQL: condition, Args: args}, fmt.Errorf("%w: %s", ErrEmptyList, condition)
+		}
+
+		sb.WriteString(markers(rv.Len()))
+		for i := 0; i < rv.Len(); i++ {
+			eargs = append(eargs, rv.Index(i).Interface())
+		}
+	}
+
+	return queryPart{SQL: sb.String(), Args: append(eargs, args[n:]...)}, nil
+}
+
+// firstError keeps the first error found while building
+func firstError(err, next error) error {
+	if err != nil {
+		return err
+	}
+	return next
+}
+
+// isListArg checks if an argument is a slice to expand. Byte slices are single values.
+func isListArg(a interface{}) bool {
+	if a == nil {
+		return false
+	}
+	t := reflect.TypeOf(a)
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8
+}
+
+// writeConditions writes conditions joined with AND and returns the arguments with theirs appended
+func writeConditions(sb *strings.Builder, keyword string, conds []queryPart, args []interface{}) []interface{} {
+	for i, c := range conds {
+		if i == 0 {
+			sb.WriteString(keyword)
+		} else {
+			sb.WriteString(` AND `)
+		}
+
+		if len(conds) > 1 {
+			sb.WriteString(`(` + c.SQL + `)`)
+		} else {
+			sb.WriteString(c.SQL)
+		}
+		args = append(args, c.Args...)
+	}
+	return args
+}
+
+// quoteNames quotes a list of names and joins them with commas
+func (dh *DataHelper) quoteNames(names []string) string {
+	q := make([]string, len(names))
+	for i, n := range names {
+		q[i] = dh.quoteName(n)
+	}
+	return strings.Join(q, `, `)
+}
+
+// quoteName quotes a name that is a reserved word or is not a plain identifier.
+// The name could be a {table} placeholder, be followed by an alias or a sort direction.
+// Expressions are returned as is.
+func (dh *DataHelper) quoteName(name string) string {
+
+	name = strings.TrimSpace(name)
+
+	// name followed by an alias or sort direction
+	head, rest := name, ""
+	if i := strings.IndexAny(name, " \t\n"); i != -1 {
+		head, rest = name[:i], name[i:]
+	}
+
+	// {table} placeholders keep their braces
+	open, close := "", ""
+	if strings.HasPrefix(head, `{`) && strings.HasSuffix(head, `}`) {
+		open, close, head = `{`, `}`, head[1:len(head)-1]
+	}
+
+	if !isDottedName(head) {
+		return name
+	}
+
+	parts := strings.Split(head, `.`)
+	for i, p := range parts {
+		if isReservedWord(p) {
+			parts[i] = dh.QuoteIdentifier(p)
+		}
+	}
+
+	return open + strings.Join(parts, `.`) + close + rest
+}
+
+// isDottedName checks if a name is made of plain identifiers separated by dots
+func isDottedName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, p := range strings.Split(name, `.`) {
+		if p == "" || !isNameStart(p[0]) {
+			return false
+		}
+		for i := 1; i < len(p); i++ {
+			if !isNameChar(p[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// reservedWords are SQL keywords reserved by at least one of the supported databases
+// that are also likely to be used as table or column names
+var reservedWords = map[string]struct{}{
+	`ADD`: {}, `ALL`: {}, `ALTER`: {}, `AND`: {}, `ANY`: {}, `AS`: {}, `ASC`: {}, `BETWEEN`: {},
+	`BY`: {}, `CASE`: {}, `CHECK`: {}, `COLUMN`: {}, `CONSTRAINT`: {}, `CREATE`: {}, `CROSS`: {},
+	`CURRENT`: {}, `CURRENT_DATE`: {}, `CURRENT_TIME`: {}, `CURRENT_USER`: {}, `DATABASE`: {},
+	`DEFAULT`: {}, `DELETE`: {}, `DESC`: {}, `DISTINCT`: {}, `DROP`: {}, `ELSE`: {}, `END`: {},
+	`EXISTS`: {}, `FETCH`: {}, `FOR`: {}, `FOREIGN`: {}, `FROM`: {}, `FULL`: {}, `FUNCTION`: {},
+	`GRANT`: {}, `GROUP`: {}, `HAVING`: {}, `IN`: {}, `INDEX`: {}, `INNER`: {}, `INSERT`: {},
+	`INTO`: {}, `IS`: {}, `JOIN`: {}, `KEY`: {}, `LEFT`: {}, `LIKE`: {}, `LIMIT`: {}, `NOT`: {},
+	`NULL`: {}, `OFFSET`: {}, `ON`: {}, `OR`: {}, `ORDER`: {}, `OUTER`: {}, `PRIMARY`: {},
+	`PROCEDURE`: {}, `REFERENCES`: {}, `RIGHT`: {}, `ROW`: {}, `ROWS`: {}, `SCHEMA`: {},
+	`SELECT`: {}, `SESSION_USER`: {}, `SET`: {}, `TABLE`: {}, `THEN`: {}, `TO`: {}, `TOP`: {},
+	`TRANSACTION`: {}, `UNION`: {}, `UNIQUE`: {}, `UPDATE`: {}, `USER`: {}, `USING`: {},
+	`VALUES`: {}, `VIEW`: {}, `WHEN`: {}, `WHERE`: {}, `WITH`: {},
+}
+
+// isReservedWord checks if a name must be quoted to be used as an identifier
+func isReservedWord(name string) bool {
+	_, ok := reservedWords[strings.ToUpper(name)]
+	return ok
+}
@@ -0,0 +1,177 @@
+package datahelper
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	cfg "github.com/eaglebush/config"
+)
+
+func TestSelectBuilder(t *testing.T) {
+	quote := `"`
+	dh := &DataHelper{
+		DriverName: `sqlserver`,
+		CurrentDatabaseInfo: &cfg.DatabaseInfo{
+			ParameterPlaceholder: `@p`,
+			ParameterInSequence:  true,
+			Schema:               `dbo`,
+		},
+		RowLimitInfo: GetDialect(`sqlserver`).RowLimiting(),
+	}
+
+	q, args := dh.Select(`UserKey`, `UserName`, `User`).
+		From(`{USERACCOUNT}`).
+		Where(`Active = ?`, 1).
+		Where(`UserKey IN (?)`, []int{3, 4}).
+		OrderBy(`UserName DESC`).
+		Limit(10).
+		ToSQL()
+
+	want := `SELECT TOP 10 UserKey, UserName, [User] FROM dbo.USERACCOUNT WHERE (Active = @p1) AND (UserKey IN (@p2, @p3)) ORDER BY UserName DESC`
+	if q != want {
+		t.Errorf("got %q", q)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, 3, 4}) {
+		t.Errorf("got args %v", args)
+	}
+
+	q, _ = dh.Select().From(`{USERACCOUNT}`).Limit(10).Offset(20).ToSQL()
+	if want = `SELECT * FROM dbo.USERACCOUNT ORDER BY (SELECT NULL) OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY`; q != want {
+		t.Errorf("got %q", q)
+	}
+
+	dh.DriverName = `postgres`
+	dh.CurrentDatabaseInfo.ParameterPlaceholder = `$`
+	dh.CurrentDatabaseInfo.ReservedWordEscapeChar = &quote
+	dh.CurrentDatabaseInfo.Schema = ``
+	dh.RowLimitInfo = GetDialect(`postgres`).RowLimiting()
+
+	q, args = dh.Select(`u.UserName`, `COUNT(*) AS n`).
+		From(`{USERACCOUNT} u`).
+		Join(`{ROLE} r`, `r.UserKey = u.UserKey AND r.Kind = ?`, `admin`).
+		Where(`u.Active = ?`, true).
+		GroupBy(`u.UserName`).
+		Having(`COUNT(*) > ?`, 1).
+		Limit(5).
+		ToSQL()
+
+	want = `SELECT u.UserName, COUNT(*) AS n FROM USERACCOUNT u INNER JOIN ROLE r ON r.UserKey = u.UserKey AND r.Kind = $1 WHERE u.Active = $2 GROUP BY u.UserName HAVING COUNT(*) > $3 LIMIT 5`
+	if q != want {
+		t.Errorf("got %q", q)
+	}
+	if !reflect.DeepEqual(args, []interface{}{`admin`, true, 1}) {
+		t.Errorf("got args %v", args)
+	}
+}
+
+func TestWriteBuilders(t *testing.T) {
+	quote := `"`
+	dh := &DataHelper{
+		DriverName: `postgres`,
+		CurrentDatabaseInfo: &cfg.DatabaseInfo{
+			ParameterPlaceholder:   `$`,
+			ParameterInSequence:    true,
+			ReservedWordEscapeChar: &quote,
+		},
+	}
+
+	tests := []struct {
+		build func() (string, []interface{})
+		want  string
+		args  []interface{}
+	}{
+		{
+			dh.InsertInto(`{USERACCOUNT}`).Columns(`UserName`, `Order`).Values(`a`, 1).Values(`b`, 2).ToSQL,
+			`INSERT INTO USERACCOUNT (UserName, "Order") VALUES ($1, $2), ($3, $4)`,
+			[]interface{}{`a`, 1, `b`, 2},
+		},
+		{
+			dh.InsertInto(`{USERACCOUNT}`).Set(`UserName`, `a`).Set(`Active`, 1).ToSQL,
+			`INSERT INTO USERACCOUNT (UserName, Active) VALUES ($1, $2)`,
+			[]interface{}{`a`, 1},
+		},
+		{
+			dh.UpdateTable(`{USERACCOUNT}`).Set(`UserName`, `a`).SetExpr(`Logins`, `Logins + ?`, 1).Where(`UserKey = ?`, 7).ToSQL,
+			`UPDATE USERACCOUNT SET UserName = $1, Logins = Logins + $2 WHERE UserKey = $3`,
+			[]interface{}{`a`, 1, 7},
+		},
+		{
+			dh.DeleteFrom(`{USERACCOUNT}`).Where(`UserKey IN (?)`, []int64{1, 2}).ToSQL,
+			`DELETE FROM USERACCOUNT WHERE UserKey IN ($1, $2)`,
+			[]interface{}{int64(1), int64(2)},
+		},
+	}
+
+	for _, tc := range tests {
+		q, args := tc.build()
+		if q != tc.want {
+			t.Errorf("got %q, want %q", q, tc.want)
+		}
+		if !reflect.DeepEqual(args, tc.args) {
+			t.Errorf("got args %v, want %v", args, tc.args)
+		}
+	}
+}
+
+func TestEmptyListArgument(t *testing.T) {
+	dh := &DataHelper{DriverName: `sqlite3`, CurrentDatabaseInfo: &cfg.DatabaseInfo{}}
+
+	sb := dh.Select().From(`{USERACCOUNT}`).Where(`UserKey IN (?)`, []int{}).Where(`Active = ?`, 1)
+	if !errors.Is(sb.Err(), ErrEmptyList) {
+		t.Fatalf("expected ErrEmptyList, got %v", sb.Err())
+	}
+	if _, err := sb.GetData(); !errors.Is(err, ErrEmptyList) {
+		t.Fatalf("expected ErrEmptyList from GetData, got %v", err)
+	}
+
+	if _, err := dh.UpdateTable(`{USERACCOUNT}`).Set(`Active`, 0).Where(`UserKey NOT IN (?)`, []string{}).Exec(); !errors.Is(err, ErrEmptyList) {
+		t.Fatalf("expected ErrEmptyList from Exec, got %v", err)
+	}
+	if _, err := dh.DeleteFrom(`{USERACCOUNT}`).Where(`UserKey IN (?)`, []int64(nil)).Exec(); !errors.Is(err, ErrEmptyList) {
+		t.Fatalf("expected ErrEmptyList from Exec, got %v", err)
+	}
+
+	// byte slices are single values
+	if sb := dh.Select().From(`{USERACCOUNT}`).Where(`Hash = ?`, []byte{}); sb.Err() != nil {
+		t.Fatalf("Error: %v", sb.Err())
+	}
+
+	// brackets are array subscripts in Postgres, not quoted names
+	dh.DriverName = `postgres`
+	q, args := dh.Select().From(`{USERACCOUNT}`).Where(`Tags[?] IN (?)`, 1, []string{`a`, `b`}).Query()
+	if q != `SELECT * FROM {USERACCOUNT} WHERE Tags[?] IN (?, ?)` || !reflect.DeepEqual(args, []interface{}{1, `a`, `b`}) {
+		t.Fatalf("unexpected query %q, %v", q, args)
+	}
+}
+
+func TestInsertValueCount(t *testing.T) {
+	dh := &DataHelper{DriverName: `sqlite3`, CurrentDatabaseInfo: &cfg.DatabaseInfo{}}
+
+	ib := dh.InsertInto(`{USERACCOUNT}`).Columns(`UserKey`, `UserName`).Values(1, `admin`).Values(2)
+	if !errors.Is(ib.Err(), ErrValueCount) {
+		t.Fatalf("expected ErrValueCount, got %v", ib.Err())
+	}
+	if _, err := ib.Exec(); !errors.Is(err, ErrValueCount) {
+		t.Fatalf("expected ErrValueCount from Exec, got %v", err)
+	}
+
+	if ib := dh.InsertInto(`{USERACCOUNT}`).Set(`UserKey`, 1).Set(`UserName`, `admin`); ib.Err() != nil {
+		t.Fatalf("Error: %v", ib.Err())
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	dh := &DataHelper{
+		DriverName:          `sqlite3`,
+		CurrentDatabaseInfo: &cfg.DatabaseInfo{},
+	}
+
+	cond, args := keysetCondition(dh, []string{`UserName DESC`, `UserKey`}, []interface{}{`m`, 5})
+	if want := `UserName < ? OR (UserName = ? AND UserKey > ?)`; cond != want {
+		t.Errorf("got %q", cond)
+	}
+	if !reflect.DeepEqual(args, []interface{}{`m`, `m`, 5}) {
+		t.Errorf("got args %v", args)
+	}
+}
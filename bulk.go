@@ -0,0 +1,300 @@
+package datahelper
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/eaglebush/datatable"
+)
+
+// BulkOptions - options of BulkInsertContext
+type BulkOptions struct {
+	BatchSize int                  // Maximum rows per batch. Default is the most the driver's parameter limit allows. 1 inserts row by row with a prepared statement.
+	Progress  func(inserted int64) // Called after every batch with the number of rows inserted so far
+}
+
+// bulkRows - rows of a bulk insert read one at a time
+type bulkRows struct {
+	count int
+	row   func(i int) []interface{}
+}
+
+// BulkInsert - inserts many rows into a table at once. The rows could be a *datatable.DataTable,
+// a [][]interface{} in the order of the columns or a slice of structs. If the columns are not
+// given, they are taken from the DataTable columns or the struct fields. It returns the number
+// of rows inserted.
+//
+// Rows are sent with the fastest way the driver has: COPY FROM STDIN on lib/pq, and multi-row
+// VALUES batches under the parameter limit on SQL Server, SQLite, MySQL and pgx. Other drivers
+// insert row by row with a prepared statement. All rows are inserted in one transaction, or in
+// a save point of the current one.
+func (dh *DataHelper) BulkInsert(table string, columns []string, rows interface{}) (int64, error) {
+	return dh.BulkInsertContext(context.Background(), table, columns, rows, BulkOptions{})
+}
+
+// BulkInsertContext - inserts many rows into a table at once with a context and options
+func (dh *DataHelper) BulkInsertContext(ctx context.Context, table string, columns []string, rows interface{}, opts BulkOptions) (inserted int64, err error) {
+
+	if table == "" {
+		return 0, ErrNoTableName
+	}
+
+	columns, br, err := readBulkRows(columns, rows)
+	if err != nil {
+		return 0, err
+	}
+
+	if br.count == 0 {
+		return 0, nil
+	}
+
+	maxParams, maxRows := bulkLimits(dh.dialect().Name())
+
+	size := opts.BatchSize
+	if max := maxParams / len(columns); size <= 0 || size > max {
+		size = max
+	}
+	if size > maxRows {
+		size = maxRows
+	}
+	if size < 1 {
+		size = 1
+	}
+
+	progress := func(n int64) {
+		if opts.Progress != nil {
+			opts.Progress(n)
+		}
+	}
+
+	err = dh.InTransaction(ctx, nil, func(tx *DataHelper) error {
+		inserted = 0
+
+		switch {
+		case strings.EqualFold(tx.DriverName, `postgres`) && opts.BatchSize != 1:
+			return tx.bulkCopy(ctx, table, columns, br, size, &inserted, progress)
+		case maxParams == 0 || size == 1:
+			return tx.bulkPrepared(ctx, table, columns, br, &inserted, progress)
+		}
+
+		for i := 0; i < br.count; i += size {
+			end := i + size
+			if end > br.count {
+				end = br.count
+			}
+
+			ib := tx.InsertInto(table).Columns(columns...)
+			for j := i; j < end; j++ {
+				ib.Values(br.row(j)...)
+			}
+
+			if _, err := ib.ExecContext(ctx); err != nil {
+				return err
+			}
+
+			inserted += int64(end - i)
+			progress(inserted)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return inserted, nil
+}
+
+// bulkCopy sends the rows with COPY FROM STDIN. lib/pq runs a prepared COPY statement in copy mode.
+func (dh *DataHelper) bulkCopy(ctx context.Context, table string, columns []string, br bulkRows, size int, inserted *int64, progress func(int64)) error {
+
+	query := `COPY ` + dh.quoteName(table) + ` (` + dh.quoteNames(columns) + `) FROM STDIN`
+	stmt, err := dh.PrepareContext(ctx, query)
+	if err != nil {
+		return dh.addError(`BulkInsert`, query, 0, err)
+	}
+	defer stmt.Close()
+
+	for i := 0; i < br.count; i++ {
+		if _, err = stmt.ExecContext(ctx, br.row(i)...); err != nil {
+			return dh.addError(`BulkInsert`, query, len(columns), err)
+		}
+
+		// rows are only written to the server when the copy buffer fills or the copy ends
+		if (i+1)%size == 0 {
+			*inserted = int64(i + 1)
+			progress(*inserted)
+		}
+	}
+
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		return dh.addError(`BulkInsert`, query, 0, err)
+	}
+
+	if *inserted != int64(br.count) {
+		*inserted = int64(br.count)
+		progress(*inserted)
+	}
+
+	return nil
+}
+
+// bulkPrepared inserts the rows one at a time with a prepared statement
+func (dh *DataHelper) bulkPrepared(ctx context.Context, table string, columns []string, br bulkRows, inserted *int64, progress func(int64)) error {
+
+	query, _ := dh.InsertInto(table).Columns(columns...).Values().Query()
+	stmt, err := dh.PrepareContext(ctx, query)
+	if err != nil {
+		return dh.addError(`BulkInsert`, query, 0, err)
+	}
+	defer stmt.Close()
+
+	for i := 0; i < br.count; i++ {
+		if _, err = stmt.ExecContext(ctx, br.row(i)...); err != nil {
+			return dh.addError(`BulkInsert`, query, len(columns), err)
+		}
+		*inserted++
+		progress(*inserted)
+	}
+
+	return nil
+}
+
+// bulkLimits returns the parameter and row limits of a statement for a dialect.
+// No parameter limit means the dialect is not known to take multi-row VALUES.
+func bulkLimits(dialect string) (maxParams, maxRows int) {
+	switch dialect {
+	case `sqlserver`:
+		// 2100 parameters per request, 1000 rows per VALUES
+		return 2099, 1000
+	case `sqlite3`:
+		return 32766, 32766
+	case `postgres`, `mysql`:
+		return 65535, 65535
+	}
+	return 0, 1
+}
+
+// readBulkRows reads the rows of a bulk insert and the columns they are in
+func readBulkRows(columns []string, rows interface{}) ([]string, bulkRows, error) {
+
+	if dt, ok := rows.(*datatable.DataTable); ok {
+		if dt == nil {
+			return nil, bulkRows{}, fmt.Errorf("Rows must not be a nil %T", rows)
+		}
+
+		if len(columns) == 0 {
+			for _, c := range dt.Columns {
+				columns = append(columns, c.Name)
+			}
+		}
+
+		idx := make([]int, len(columns))
+		for i, c := range columns {
+			idx[i] = -1
+			for j, dc := range dt.Columns {
+				if strings.EqualFold(dc.Name, c) {
+					idx[i] = j
+					break
+				}
+			}
+			if idx[i] == -1 {
+				return nil, bulkRows{}, fmt.Errorf("%w: %s is not in the data table", ErrNoColumn, c)
+			}
+		}
+
+		return columns, bulkRows{
+			count: dt.RowCount,
+			row: func(i int) []interface{} {
+				vals := make([]interface{}, len(idx))
+				for k, j := range idx {
+					vals[k] = dt.Rows[i].Cells[j].Value
+				}
+				return vals
+			},
+		}, checkBulkColumns(columns)
+	}
+
+	if vals, ok := rows.([][]interface{}); ok {
+		for i, r := range vals {
+			if len(r) != len(columns) {
+				return nil, bulkRows{}, fmt.Errorf("Row %d has %d values for %d columns", i, len(r), len(columns))
+			}
+		}
+
+		return columns, bulkRows{
+			count: len(vals),
+			row:   func(i int) []interface{} { return vals[i] },
+		}, checkBulkColumns(columns)
+	}
+
+	rv := reflect.ValueOf(rows)
+	if rv.Kind() != reflect.Slice {
+		return nil, bulkRows{}, fmt.Errorf("Rows must be a *datatable.DataTable, [][]interface{} or a slice of structs, not %T", rows)
+	}
+
+	et := rv.Type().Elem()
+	ptr := et.Kind() == reflect.Pointer
+	if ptr {
+		et = et.Elem()
+	}
+
+	if et.Kind() != reflect.Struct {
+		return nil, bulkRows{}, fmt.Errorf("Rows must be a *datatable.DataTable, [][]interface{} or a slice of structs, not %T", rows)
+	}
+
+	var flds []structFieldInfo
+	if len(columns) == 0 {
+		for _, f := range structFields(et) {
+			if !f.ReadOnly {
+				columns = append(columns, f.Name)
+				flds = append(flds, f)
+			}
+		}
+	} else {
+		for _, c := range columns {
+			found := false
+			for _, f := range structFields(et) {
+				if strings.EqualFold(f.Name, c) {
+					flds = append(flds, f)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, bulkRows{}, fmt.Errorf("%w: %s is not a field of %s", ErrNoColumn, c, et)
+			}
+		}
+	}
+
+	return columns, bulkRows{
+		count: rv.Len(),
+		row: func(i int) []interface{} {
+			ev := rv.Index(i)
+			if ptr {
+				ev = ev.Elem()
+			}
+
+			vals := make([]interface{}, len(flds))
+			for k, f := range flds {
+				// fields of a nil embedded struct pointer or a nil row are written as NULL
+				if ev.IsValid() {
+					if fv, err := ev.FieldByIndexErr(f.Index); err == nil {
+						vals[k] = fv.Interface()
+					}
+				}
+			}
+			return vals
+		},
+	}, checkBulkColumns(columns)
+}
+
+func checkBulkColumns(columns []string) error {
+	if len(columns) == 0 {
+		return ErrNoColumn
+	}
+	return nil
+}
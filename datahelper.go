@@ -1,9 +1,9 @@
 package datahelper
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -28,14 +28,18 @@ const (
 type DataHelper struct {
 	db                  *sql.DB
 	tx                  *sql.Tx
+	conn                *Connection // shared connection the helper borrows from
+	savepoints          []string    // active save points of the transaction
 	connectionString    string
 	DriverName          string            // Driver name set in the configuration file
 	ConnectionID        string            // Connection ID set in the configuration file
 	AllQueryOK          bool              // Flags if all queries are ok in a non-transaction mode
 	Errors              []string          // Errors encountered
+	QueryErrors         []error           // Errors encountered with their original error chain
 	Settings            cfg.Configuration // Settings from the configuration
 	CurrentDatabaseInfo *cfg.DatabaseInfo // Current database information
 	RowLimitInfo        RowLimiting       // Row limiting information
+	Dialect             Dialect           // SQL dialect of the driver
 }
 
 // RowLimitPlacement - row limit placement of row limits
@@ -93,6 +97,7 @@ func NewDataHelper(config *cfg.Configuration) *DataHelper {
 
 // NewConnected creates a new connected datahelper.
 // The dh parameter could optionally be supplied by a valid datahelper.
+// Helpers connecting to the same database share its pool, which stays open until Shutdown.
 // Returns : DataHelper, InTransaction and Error
 func NewConnected(dh *DataHelper, config *cfg.Configuration, ConnectionID ...string) (*DataHelper, bool, error) {
 
@@ -135,6 +140,11 @@ func (dh *DataHelper) Connect(ConnectionID ...string) (connected bool, err error
 
 // GetRow - get a single row result from a query
 func (dh *DataHelper) GetRow(columns []string, tableNameWithParameters string, args ...interface{}) (SingleRow, error) {
+	return dh.GetRowContext(context.Background(), columns, tableNameWithParameters, args...)
+}
+
+// GetRowContext - get a single row result from a query with a context
+func (dh *DataHelper) GetRowContext(ctx context.Context, columns []string, tableNameWithParameters string, args ...interface{}) (SingleRow, error) {
 
 	var (
 		err   error
@@ -149,11 +159,15 @@ func (dh *DataHelper) GetRow(columns []string, tableNameWithParameters string, a
 	r.Row = datatable.Row{}
 
 	if len(columns) == 0 {
-		return r, errors.New("No column was specified")
+		return r, ErrNoColumn
 	}
 
 	if tableNameWithParameters == "" {
-		return r, errors.New("No tablename was specified")
+		return r, ErrNoTableName
+	}
+
+	if dh.tx == nil && dh.db == nil {
+		return r, ErrNotConnected
 	}
 
 	cma = ""
@@ -169,13 +183,12 @@ func (dh *DataHelper) GetRow(columns []string, tableNameWithParameters string, a
 	query = replaceCustomPlaceHolder(query, dh.CurrentDatabaseInfo.Schema)
 
 	if dh.tx != nil {
-		row = dh.tx.QueryRow(query, args...)
+		row = dh.tx.QueryRowContext(ctx, query, args...)
 	} else {
 		//If the query is not in a transaction, the following properties are always reset
-		dh.AllQueryOK = true
-		dh.Errors = make([]string, 0)
+		dh.resetErrors()
 
-		row = dh.db.QueryRow(query, args...)
+		row = dh.db.QueryRowContext(ctx, query, args...)
 	}
 
 	lencols := len(columns)
@@ -190,9 +203,7 @@ func (dh *DataHelper) GetRow(columns []string, tableNameWithParameters string, a
 
 		norows = errors.Is(err, sql.ErrNoRows)
 		if !norows {
-			dh.Errors = append(dh.Errors, err.Error())
-			dh.AllQueryOK = false
-			return r, err
+			return r, dh.addError(`GetRow`, query, len(args), err)
 		}
 
 		err = nil
@@ -226,6 +237,11 @@ func (dh *DataHelper) GetRow(columns []string, tableNameWithParameters string, a
 
 // GetData - get data from the database and return in a tabular form
 func (dh *DataHelper) GetData(preparedQuery string, arg ...interface{}) (*datatable.DataTable, error) {
+	return dh.GetDataContext(context.Background(), preparedQuery, arg...)
+}
+
+// GetDataContext - get data from the database with a context and return in a tabular form
+func (dh *DataHelper) GetDataContext(ctx context.Context, preparedQuery string, arg ...interface{}) (*datatable.DataTable, error) {
 
 	dt := datatable.NewDataTable("data")
 
@@ -233,19 +249,22 @@ func (dh *DataHelper) GetData(preparedQuery string, arg ...interface{}) (*datata
 	var err error
 	colsadded := false
 
+	if dh.tx == nil && dh.db == nil {
+		return dt, ErrNotConnected
+	}
+
 	query := dh.replaceQueryParamMarker(preparedQuery)
 
 	// replace table names marked with {table}
 	query = replaceCustomPlaceHolder(query, dh.CurrentDatabaseInfo.Schema)
 
 	if dh.tx != nil {
-		rows, err = dh.tx.Query(query, arg...)
+		rows, err = dh.tx.QueryContext(ctx, query, arg...)
 	} else {
 		//If the query is not in a transaction, the following properties are always reset
-		dh.AllQueryOK = true
-		dh.Errors = make([]string, 0)
+		dh.resetErrors()
 
-		rows, err = dh.db.Query(query, arg...)
+		rows, err = dh.db.QueryContext(ctx, query, arg...)
 	}
 
 	defer func() {
@@ -255,9 +274,7 @@ func (dh *DataHelper) GetData(preparedQuery string, arg ...interface{}) (*datata
 	}()
 
 	if err != nil {
-		dh.Errors = append(dh.Errors, err.Error())
-		dh.AllQueryOK = false
-		return dt, err
+		return dt, dh.addError(`GetData`, query, len(arg), err)
 	}
 
 	cols, _ := rows.Columns()
@@ -298,16 +315,28 @@ func (dh *DataHelper) GetData(preparedQuery string, arg ...interface{}) (*datata
 	}
 
 	// Get possible error in the iteration
-	err = rows.Err()
+	if err = rows.Err(); err != nil {
+		return dt, dh.addError(`GetData`, query, len(arg), err)
+	}
 
-	return dt, err
+	return dt, nil
 }
 
 // Exec - execute queries that does not return rows such us INSERT, DELETE and UPDATE
 func (dh *DataHelper) Exec(preparedQuery string, arg ...interface{}) (sql.Result, error) {
+	return dh.ExecContext(context.Background(), preparedQuery, arg...)
+}
+
+// ExecContext - execute queries that does not return rows such us INSERT, DELETE and UPDATE with a context
+func (dh *DataHelper) ExecContext(ctx context.Context, preparedQuery string, arg ...interface{}) (sql.Result, error) {
 	var result sql.Result
 	var err error
 
+	if dh.tx == nil && dh.db == nil {
+		dh.AllQueryOK = false
+		return nil, ErrNotConnected
+	}
+
 	query := dh.replaceQueryParamMarker(preparedQuery)
 
 	// replace table names marked with {table}
@@ -315,31 +344,38 @@ func (dh *DataHelper) Exec(preparedQuery string, arg ...interface{}) (sql.Result
 
 	if dh.tx != nil {
 
-		if result, err = dh.tx.Exec(query, arg...); err != nil {
-			dh.AllQueryOK = false
-			dh.Errors = append(dh.Errors, err.Error())
+		if result, err = dh.tx.ExecContext(ctx, query, arg...); err != nil {
+			return result, dh.addError(`Exec`, query, len(arg), err)
 		}
 
-		return result, err
+		return result, nil
 	}
 
-	if dh.tx == nil {
-		//If the query is not in a transaction, the following properties are always reset
-		dh.AllQueryOK = true
-		dh.Errors = make([]string, 0)
+	//If the query is not in a transaction, the following properties are always reset
+	dh.resetErrors()
 
-		return dh.db.Exec(query, arg...)
+	if result, err = dh.db.ExecContext(ctx, query, arg...); err != nil {
+		return result, dh.addError(`Exec`, query, len(arg), err)
 	}
 
-	dh.AllQueryOK = false
-	return nil, errors.New("Unknown execution error")
+	return result, nil
 }
 
 // Begin - begins a new transaction
 func (dh *DataHelper) Begin(intr bool) (*sql.Tx, error) {
+	return dh.BeginContext(context.Background(), nil, intr)
+}
+
+// BeginContext - begins a new transaction with a context and transaction options.
+// The options could be nil to use the driver defaults.
+func (dh *DataHelper) BeginContext(ctx context.Context, opts *sql.TxOptions, intr bool) (*sql.Tx, error) {
 
 	if intr {
-		return nil, errors.New(`DataHelper does not allow a new transaction`)
+		return nil, ErrNestedTransaction
+	}
+
+	if dh.db == nil {
+		return nil, ErrNotConnected
 	}
 
 	var tx *sql.Tx
@@ -347,39 +383,47 @@ func (dh *DataHelper) Begin(intr bool) (*sql.Tx, error) {
 
 	dh.AllQueryOK = true
 
-	if tx, err = dh.db.Begin(); err != nil {
+	if tx, err = dh.db.BeginTx(ctx, opts); err != nil {
 		return nil, err
 	}
 
 	dh.tx = tx
+	dh.savepoints = nil
 	return tx, nil
 }
 
 // GetDataReader - returns a DataTable Row with an internal sql.Row object for iteration.
 func (dh *DataHelper) GetDataReader(preparedQuery string, arg ...interface{}) (datatable.Row, error) {
+	return dh.GetDataReaderContext(context.Background(), preparedQuery, arg...)
+}
+
+// GetDataReaderContext - returns a DataTable Row with an internal sql.Row object for iteration.
+// The context must stay alive until the reader is closed.
+func (dh *DataHelper) GetDataReaderContext(ctx context.Context, preparedQuery string, arg ...interface{}) (datatable.Row, error) {
 	row := datatable.Row{}
 
 	var rows *sql.Rows
 	var err error
 
+	if dh.tx == nil && dh.db == nil {
+		return row, ErrNotConnected
+	}
+
 	query := dh.replaceQueryParamMarker(preparedQuery)
 	// replace table names marked with {table}
 	query = replaceCustomPlaceHolder(query, dh.CurrentDatabaseInfo.Schema)
 
 	if dh.tx != nil {
-		rows, err = dh.tx.Query(query, arg...)
+		rows, err = dh.tx.QueryContext(ctx, query, arg...)
 	} else {
 		//If the query is not in a transaction, the following properties are always reset
-		dh.AllQueryOK = true
-		dh.Errors = make([]string, 0)
+		dh.resetErrors()
 
-		rows, err = dh.db.Query(query, arg...)
+		rows, err = dh.db.QueryContext(ctx, query, arg...)
 	}
 
 	if err != nil {
-		dh.Errors = append(dh.Errors, err.Error())
-		dh.AllQueryOK = false
-		return row, err
+		return row, dh.addError(`GetDataReader`, query, len(arg), err)
 	}
 
 	//Set the pointer to the returned rows
@@ -393,20 +437,20 @@ func (dh *DataHelper) GetDataReader(preparedQuery string, arg ...interface{}) (d
 func (dh *DataHelper) Commit(intr bool) error {
 
 	if intr {
-		return errors.New(`DataHelper does not allow to rollback a parent transaction`)
+		return ErrParentTransaction
 	}
 
 	if dh.tx == nil {
-		return errors.New("No transaction was initiated")
+		return ErrNoTransaction
 	}
 
 	var err error
 
 	//The following properties are always reset after commit
-	dh.AllQueryOK = true
-	dh.Errors = make([]string, 0)
+	dh.resetErrors()
 	if err = dh.tx.Commit(); err == nil {
 		dh.tx = nil
+		dh.savepoints = nil
 	}
 
 	return err
@@ -416,20 +460,20 @@ func (dh *DataHelper) Commit(intr bool) error {
 func (dh *DataHelper) Rollback(intr bool) error {
 
 	if intr {
-		return errors.New(`DataHelper does not allow to rollback a parent transaction`)
+		return ErrParentTransaction
 	}
 
 	if dh.tx == nil {
-		return errors.New("No transaction was initiated")
+		return ErrNoTransaction
 	}
 
 	var err error
 
 	//The following properties are always reset after rollback
-	dh.AllQueryOK = true
-	dh.Errors = make([]string, 0)
+	dh.resetErrors()
 	if err = dh.tx.Rollback(); err == nil {
 		dh.tx = nil
+		dh.savepoints = nil
 	}
 
 	return err
@@ -437,33 +481,52 @@ func (dh *DataHelper) Rollback(intr bool) error {
 
 // Prepare - prepare a statement
 func (dh *DataHelper) Prepare(preparedQuery string) (*sql.Stmt, error) {
+	return dh.PrepareContext(context.Background(), preparedQuery)
+}
+
+// PrepareContext - prepare a statement with a context
+func (dh *DataHelper) PrepareContext(ctx context.Context, preparedQuery string) (*sql.Stmt, error) {
+
+	if dh.tx == nil && dh.db == nil {
+		return nil, ErrNotConnected
+	}
+
 	query := dh.replaceQueryParamMarker(preparedQuery)
 
 	// replace table names marked with {table}
 	query = replaceCustomPlaceHolder(query, dh.CurrentDatabaseInfo.Schema)
 
 	if dh.tx != nil {
-		return dh.tx.Prepare(query)
+		return dh.tx.PrepareContext(ctx, query)
 	}
 
-	if dh.db != nil {
-		return dh.db.Prepare(query)
-	}
-
-	return nil, errors.New(`No active connections`)
+	return dh.db.PrepareContext(ctx, query)
 }
 
-// Disconnect - disconnect from the database
+// Disconnect - disconnect from the database.
+//
+// Helpers connected with Connect or NewConnected share a pool per database, kept in the default
+// registry. The pool stays open for the next helper connecting to the database, until Shutdown
+// or DefaultRegistry().ClosePool closes it. Sessions leave the pool of their Connection open.
 func (dh *DataHelper) Disconnect(intr bool) error {
 
 	if intr {
-		return errors.New(`DataHelper does not disconnect a parent connection`)
+		return ErrParentConnection
 	}
 
 	dh.tx = nil
+	dh.savepoints = nil
 	if dh.db == nil {
 		return nil
 	}
+
+	// Helpers leave the shared connection open
+	if dh.conn != nil {
+		dh.db = nil
+		dh.conn = nil
+		return nil
+	}
+
 	return dh.db.Close()
 }
 
@@ -472,14 +535,55 @@ func (dh *DataHelper) IsInTransaction() bool {
 	return dh.tx != nil
 }
 
-// GetSequence - get the next sequence based on the sequence key
+// GetSequence - get the next sequence based on the sequence key.
+//
+// The SequenceGenerator queries of the connection are used if they are configured. Otherwise,
+// the native sequence of the database is used, and created on first use.
 func (dh *DataHelper) GetSequence(SequenceKey string) (string, error) {
+	return dh.GetSequenceContext(context.Background(), SequenceKey)
+}
+
+// GetSequenceContext - get the next sequence based on the sequence key with a context
+func (dh *DataHelper) GetSequenceContext(ctx context.Context, SequenceKey string) (string, error) {
+
+	sq, ok, err := dh.nextSequence(ctx, SequenceKey, false)
+	if err != nil || !ok {
+		return "", err
+	}
+
+	return strconv.FormatInt(sq, 10), nil
+}
+
+// GetSequenceInt64 - get the next sequence based on the sequence key as a number.
+// It returns sql.ErrNoRows if the configured result query has no result.
+func (dh *DataHelper) GetSequenceInt64(SequenceKey string) (int64, error) {
+	return dh.GetSequenceInt64Context(context.Background(), SequenceKey)
+}
+
+// GetSequenceInt64Context - get the next sequence based on the sequence key as a number with a context
+func (dh *DataHelper) GetSequenceInt64Context(ctx context.Context, SequenceKey string) (int64, error) {
+
+	sq, ok, err := dh.nextSequence(ctx, SequenceKey, false)
+	if err == nil && !ok {
+		err = sql.ErrNoRows
+	}
+
+	return sq, err
+}
+
+// nextSequence gets the next sequence and if the sequence returned a value. Counters are kept
+// as rows of the key generator table on every database instead of native sequences.
+func (dh *DataHelper) nextSequence(ctx context.Context, SequenceKey string, counter bool) (int64, bool, error) {
 
 	var (
 		err error
 		dt  *datatable.DataTable
 	)
 
+	if err = checkSequenceName(SequenceKey); err != nil {
+		return 0, false, err
+	}
+
 	//var sr SingleRow
 	if dh.ConnectionID == "" {
 		dh.ConnectionID = *dh.Settings.DefaultDatabaseID
@@ -489,51 +593,66 @@ func (dh *DataHelper) GetSequence(SequenceKey string) (string, error) {
 
 	si := conninfo.SequenceGenerator
 
-	if si == nil {
-		return "", errors.New("No sequence generator queries were defined")
+	size := sequenceBlockSize(SequenceKey)
+
+	// configured queries override the native sequence
+	if si == nil || (len(si.UpsertQuery) == 0 && len(si.ResultQuery) == 0) {
+		var sq int64
+		switch {
+		case counter:
+			sq, err = dh.keyGeneratorSequence(ctx, SequenceKey, 1)
+		case size > 1:
+			sq, err = dh.blockSequence(ctx, SequenceKey, size)
+		default:
+			sq, err = dh.nativeSequence(ctx, SequenceKey)
+		}
+		return sq, err == nil, err
 	}
 
-	if len(si.UpsertQuery) == 0 && len(si.ResultQuery) == 0 {
-		return "", errors.New("Sequence upsert or result query was not configured")
+	if size > 1 {
+		return 0, false, ErrNoBlockSequence
 	}
 
 	if len(si.NamePlaceHolder) == 0 {
-		return "", errors.New("Sequence name placeholder was not configured")
+		return 0, false, ErrNoSequencePlaceholder
 	}
 
-	upsertq := strings.Replace(si.UpsertQuery, si.NamePlaceHolder, SequenceKey, -1)
-	resultq := strings.Replace(si.ResultQuery, si.NamePlaceHolder, SequenceKey, -1)
-
-	// replace table names marked with {table}
-	sch := dh.CurrentDatabaseInfo.Schema
-	if sch != "" {
-		sch = sch + `.`
+	// the sequence key is bound as a parameter wherever the name placeholder is
+	upsertq, uargs, err := bindSequenceName(si.UpsertQuery, si.NamePlaceHolder, SequenceKey)
+	if err != nil {
+		return 0, false, err
 	}
 
-	re := regexp.MustCompile(`\{(\w*)\}`)
-	upsertq = re.ReplaceAllString(upsertq, sch+`$1`)
-	resultq = re.ReplaceAllString(resultq, sch+`$1`)
+	resultq, rargs, err := bindSequenceName(si.ResultQuery, si.NamePlaceHolder, SequenceKey)
+	if err != nil {
+		return 0, false, err
+	}
 
 	/* Update generator */
-	if _, err = dh.Exec(upsertq); err != nil {
-		return "", err
+	if strings.TrimSpace(upsertq) != "" {
+		if _, err = dh.ExecContext(ctx, upsertq, uargs...); err != nil {
+			return 0, false, err
+		}
 	}
 
-	if dt, err = dh.GetData(resultq); err != nil {
-		return "", err
+	if dt, err = dh.GetDataContext(ctx, resultq, rargs...); err != nil {
+		return 0, false, err
 	}
 
 	if dt.RowCount > 0 {
-		sq := dt.Rows[0].ValueInt64Ord(0)
-		s := strconv.FormatInt(sq, 10)
-		return s, nil
+		return dt.Rows[0].ValueInt64Ord(0), true, nil
 	}
 
-	return "", nil
+	return 0, false, nil
 }
 
 // Exists - checks if the record exists
 func (dh *DataHelper) Exists(tableNameWithParameters string, args ...interface{}) (bool, error) {
+	return dh.ExistsContext(context.Background(), tableNameWithParameters, args...)
+}
+
+// ExistsContext - checks if the record exists with a context
+func (dh *DataHelper) ExistsContext(ctx context.Context, tableNameWithParameters string, args ...interface{}) (bool, error) {
 
 	var (
 		err     error
@@ -543,7 +662,11 @@ func (dh *DataHelper) Exists(tableNameWithParameters string, args ...interface{}
 	)
 
 	if tableNameWithParameters == "" {
-		return false, errors.New("No tablename was specified")
+		return false, ErrNoTableName
+	}
+
+	if dh.tx == nil && dh.db == nil {
+		return false, ErrNotConnected
 	}
 
 	query = "SELECT "
@@ -566,21 +689,18 @@ func (dh *DataHelper) Exists(tableNameWithParameters string, args ...interface{}
 	query = replaceCustomPlaceHolder(query, dh.CurrentDatabaseInfo.Schema)
 
 	if dh.tx != nil {
-		row = dh.tx.QueryRow(query, args...)
+		row = dh.tx.QueryRowContext(ctx, query, args...)
 	} else {
-		dh.AllQueryOK = true
-		dh.Errors = make([]string, 0)
+		dh.resetErrors()
 
-		row = dh.db.QueryRow(query, args...)
+		row = dh.db.QueryRowContext(ctx, query, args...)
 	}
 
 	singval = new(interface{})
 
 	if err = row.Scan(singval); err != nil {
 		if !errors.Is(err, sql.ErrNoRows) {
-			dh.Errors = append(dh.Errors, err.Error())
-			dh.AllQueryOK = false
-			return false, err
+			return false, dh.addError(`Exists`, query, len(args), err)
 		}
 
 		return false, nil
@@ -618,108 +738,215 @@ func (dh *DataHelper) SetConnMaxLifetime(d time.Duration) {
 	dh.db.SetConnMaxLifetime(d)
 }
 
-// Mark - starts a named transaction to simulate a save point
+// Mark - sets a save point in the current transaction
 func (dh *DataHelper) Mark(PointID string) error {
+	return dh.MarkContext(context.Background(), PointID)
+}
 
-	if dh.tx == nil {
-		return errors.New("The current DataHelper instance is not in a built-in transaction")
-	}
+// MarkContext - sets a save point in the current transaction with a context
+func (dh *DataHelper) MarkContext(ctx context.Context, PointID string) error {
 
-	if PointID == "" {
-		return errors.New("No point id was specified")
+	if err := dh.checkPointID(PointID); err != nil {
+		return err
 	}
 
 	// Get keyword from the config
-	kw := `SAVE TRANSACTION`
-	if km := dh.CurrentDatabaseInfo.KeywordMap; km != nil && len(*km) > 0 {
-		for _, kv := range *km {
-			if strings.ToLower(kv.Key) == `savepoint_start` {
-				kw = *kv.Value
-				break
-			}
-		}
+	sp := dh.dialect().Savepoint(PointID)
+	if kw, ok := dh.keyword(`savepoint_start`); ok {
+		sp = kw + ` ` + PointID
 	}
 
-	// Begin nested transaction
-	if _, err := dh.Exec(kw + ` ` + PointID + `;`); err != nil {
+	if _, err := dh.ExecContext(ctx, sp+`;`); err != nil {
 		return err
 	}
 
+	dh.savepoints = append(dh.savepoints, PointID)
 	return nil
 }
 
-// Discard - rejects a named transaction to simulate a save point
+// Discard - rolls back the changes made after a save point. Save points set after it are dropped.
+// The save point itself is kept and could be discarded again.
 func (dh *DataHelper) Discard(PointID string) error {
-	if dh.tx == nil {
-		return errors.New("The current DataHelper instance is not in a built-in transaction")
+	return dh.DiscardContext(context.Background(), PointID)
+}
+
+// DiscardContext - rolls back the changes made after a save point with a context
+func (dh *DataHelper) DiscardContext(ctx context.Context, PointID string) error {
+
+	if err := dh.checkPointID(PointID); err != nil {
+		return err
 	}
 
-	if PointID == "" {
-		return errors.New("No point id was specified to reject a save point")
+	i := dh.savepointIndex(PointID)
+	if i == -1 {
+		return &UnknownPointError{PointID: PointID}
 	}
 
-	// Get keyword from the config
-	kw := `ROLLBACK TRANSACTION`
-	if km := dh.CurrentDatabaseInfo.KeywordMap; km != nil && len(*km) > 0 {
-		for _, kv := range *km {
-			if strings.ToLower(kv.Key) == `savepoint_release` {
-				kw = *kv.Value
-				break
-			}
-		}
+	// Get keyword from the config. The savepoint_release key is kept for older configurations.
+	sp := dh.dialect().RollbackToSavepoint(PointID)
+	if kw, ok := dh.keyword(`savepoint_release`); ok {
+		sp = kw + ` ` + PointID
 	}
 
-	// Begin nested transaction
-	if _, err := dh.Exec(kw + ` ` + PointID + `;`); err != nil {
+	if _, err := dh.ExecContext(ctx, sp+`;`); err != nil {
 		return err
 	}
 
+	dh.savepoints = dh.savepoints[:i+1]
 	return nil
 }
 
-func (dh *DataHelper) replaceQueryParamMarker(preparedQuery string) string {
-	var paramchar string
+// Release - releases a save point, keeping its changes in the transaction.
+// Save points set after it are released with it.
+func (dh *DataHelper) Release(PointID string) error {
+	return dh.ReleaseContext(context.Background(), PointID)
+}
+
+// ReleaseContext - releases a save point with a context
+func (dh *DataHelper) ReleaseContext(ctx context.Context, PointID string) error {
+
+	if err := dh.checkPointID(PointID); err != nil {
+		return err
+	}
+
+	i := dh.savepointIndex(PointID)
+	if i == -1 {
+		return &UnknownPointError{PointID: PointID}
+	}
+
+	// Get keyword from the config
+	sp := dh.dialect().ReleaseSavepoint(PointID)
+	if kw, ok := dh.keyword(`savepoint_commit`); ok {
+		sp = kw + ` ` + PointID
+	}
+
+	// Some databases, like SQL Server, have no release
+	if sp != "" {
+		if _, err := dh.ExecContext(ctx, sp+`;`); err != nil {
+			return err
+		}
+	}
+
+	dh.savepoints = dh.savepoints[:i]
+	return nil
+}
+
+// Savepoints - returns the active save points, from the outermost to the innermost
+func (dh *DataHelper) Savepoints() []string {
+	return append([]string(nil), dh.savepoints...)
+}
+
+// UnknownPointError - the save point was not set in the current transaction
+type UnknownPointError struct {
+	PointID string
+}
+
+func (e *UnknownPointError) Error() string {
+	return "Save point " + e.PointID + " was not set in the current transaction"
+}
 
-	retstr := preparedQuery
-	pattern := `\?` //search for ?
+// Unwrap - allows errors.Is(err, ErrUnknownPoint)
+func (e *UnknownPointError) Unwrap() error {
+	return ErrUnknownPoint
+}
 
-	paramseq := dh.CurrentDatabaseInfo.ParameterInSequence
+// checkPointID checks if the transaction is active and the point id is a valid name
+func (dh *DataHelper) checkPointID(PointID string) error {
 
-	if paramchar = dh.CurrentDatabaseInfo.ParameterPlaceholder; paramchar == `?` {
-		return retstr
+	if dh.tx == nil {
+		return ErrNotInTransaction
 	}
 
-	re := regexp.MustCompile(pattern)
-	matches := re.FindAllString(preparedQuery, -1)
+	if PointID == "" {
+		return ErrNoPointID
+	}
 
-	for i, match := range matches {
-		if paramseq {
-			retstr = strings.Replace(retstr, match, paramchar+strconv.Itoa((i+1)), 1)
-		} else {
-			retstr = strings.Replace(retstr, match, paramchar, 1)
+	for i := 0; i < len(PointID); i++ {
+		if (i == 0 && !isNameStart(PointID[i])) || !isNameChar(PointID[i]) {
+			return ErrInvalidPointID
 		}
 	}
 
-	return retstr
+	return nil
 }
 
-func getRowLimiting(driverName string) RowLimiting {
+// savepointIndex returns the index of the most recent save point with the id
+func (dh *DataHelper) savepointIndex(PointID string) int {
+	for i := len(dh.savepoints) - 1; i >= 0; i-- {
+		if strings.EqualFold(dh.savepoints[i], PointID) {
+			return i
+		}
+	}
+	return -1
+}
 
-	// default row limiting function (mysql, postgres and sqlite3)
-	rl := RowLimiting{
-		Keyword:   "LIMIT",
-		Placement: RowLimitingRear,
+// keyword gets a keyword equivalent from the configuration
+func (dh *DataHelper) keyword(key string) (string, bool) {
+	if dh.CurrentDatabaseInfo == nil {
+		return "", false
 	}
 
-	switch driverName {
-	case "mssql", "sqlserver":
-		rl = RowLimiting{
-			Keyword:   "TOP",
-			Placement: RowLimitingFront,
+	if km := dh.CurrentDatabaseInfo.KeywordMap; km != nil && len(*km) > 0 {
+		for _, kv := range *km {
+			if strings.ToLower(kv.Key) == key && kv.Value != nil {
+				return *kv.Value, true
+			}
 		}
 	}
 
-	return rl
+	return "", false
+}
+
+// replaceQueryParamMarker rewrites the ? parameter markers into the placeholders of the current connection.
+// Markers inside literals, comments and quoted identifiers are left untouched.
+func (dh *DataHelper) replaceQueryParamMarker(preparedQuery string) string {
+	var paramchar string
+
+	if paramchar = dh.CurrentDatabaseInfo.ParameterPlaceholder; paramchar == `?` || paramchar == "" {
+		return preparedQuery
+	}
+
+	return rewriteSQL(
+		preparedQuery,
+		dh.syntax(),
+		parameterMarker(paramchar, dh.CurrentDatabaseInfo.ParameterInSequence),
+		nil)
+}
+
+// syntax gets the dialect specific syntax of the queries of the current connection
+func (dh *DataHelper) syntax() sqlSyntax {
+	return sqlSyntax{
+		brackets: dh.bracketIdentifiers(),
+		jsonOps:  dh.dialect().Name() == `postgres`,
+	}
+}
+
+// bracketIdentifiers checks if the current connection quotes identifiers with brackets
+func (dh *DataHelper) bracketIdentifiers() bool {
+	switch strings.ToLower(dh.DriverName) {
+	case "postgres", "pgx":
+		// brackets are array subscripts in Postgres
+		return false
+	}
+	return true
+}
+
+// dialect returns the dialect of the connection, even if the helper was not connected
+func (dh *DataHelper) dialect() Dialect {
+	if dh.Dialect == nil {
+		return GetDialect(dh.DriverName)
+	}
+	return dh.Dialect
+}
+
+// QuoteIdentifier - quotes a table or column name with the configured reserved word escape characters.
+// If none were configured, the quoting of the dialect is used.
+func (dh *DataHelper) QuoteIdentifier(name string) string {
+	if di := dh.CurrentDatabaseInfo; di != nil && di.ReservedWordEscapeChar != nil && *di.ReservedWordEscapeChar != "" {
+		rwe := parseReserveWordsChars(*di.ReservedWordEscapeChar)
+		return quoteIdentifier(name, rwe[0], rwe[1])
+	}
+	return dh.dialect().QuoteIdentifier(name)
 }
 
 // get query column public name
@@ -787,82 +1014,31 @@ func parseReserveWordsChars(ec string) []string {
 
 func connect(prevdh *DataHelper, connectid string, config *cfg.Configuration) (dh *DataHelper, connected bool, err error) {
 
-	dh = prevdh
-	if prevdh == nil {
-		dh = &DataHelper{
-			Settings: *config,
-		}
-	}
-
-	dh.ConnectionID = connectid
-	if dh.ConnectionID == "" {
-		dh.ConnectionID = *dh.Settings.DefaultDatabaseID
-	}
-
-	if dh.CurrentDatabaseInfo = config.GetDatabaseInfo(dh.ConnectionID); dh.CurrentDatabaseInfo == nil {
-		dh = nil
-		err = errors.New("Connection ID does not exist")
-		return
-	}
-
-	di := dh.CurrentDatabaseInfo
-
-	dh.DriverName = di.DriverName
-	dh.RowLimitInfo = getRowLimiting(dh.DriverName)
-
-	if len(di.ConnectionString) == 0 {
-		dh = nil
-		err = errors.New("Connection string is not set")
-		return
-	}
-
-	dh.connectionString = di.ConnectionString
-
-	if dh.db, err = sql.Open(di.DriverName, di.ConnectionString); err != nil {
-		dh = nil
-		return
-	}
-
-	if di.MaxOpenConnection != nil && *di.MaxOpenConnection != 0 {
-		dh.db.SetMaxOpenConns(*di.MaxOpenConnection)
-	}
-
-	if di.MaxIdleConnection != nil && *di.MaxIdleConnection != 0 {
-		dh.db.SetMaxIdleConns(*di.MaxIdleConnection)
-	}
+	var c *Connection
 
-	if maxlt := di.MaxConnectionLifetime; maxlt != nil && *maxlt != 0 {
-		dh.db.SetConnMaxLifetime(time.Hour * time.Duration(*maxlt))
+	// The pool is shared by all helpers connecting to the same database
+	if c, err = defaultRegistry.Connection(config, connectid); err != nil {
+		return nil, false, err
 	}
 
-	if di.StorageType != "FILE" {
-		if di.Ping != nil && *di.Ping {
-			if err = dh.db.Ping(); err != nil {
-				dh = nil
-				return
-			}
-		}
+	dh = prevdh
+	if prevdh == nil {
+		dh = &DataHelper{}
 	}
 
-	/*
-		Resets errors and assumes all queries are OK.
-		AllQueryOK is primarily used in a batch of queries.
-		This will be set to false if one of the query execution fails.
-	*/
-	dh.Errors = make([]string, 0)
-	dh.AllQueryOK = true
-	connected = true
+	c.attach(dh)
 
-	return
+	return dh, true, nil
 }
 
+// replaceCustomPlaceHolder replaces table names marked with {table} with the schema qualified name.
+// Placeholders inside literals and comments are left untouched.
 func replaceCustomPlaceHolder(sql string, schema string) string {
 	if schema != "" {
 		schema = schema + `.`
 	}
 
-	re := regexp.MustCompile(`\{([a-zA-Z0-9\[\]\"\_\-]*)\}`)
-	sql = re.ReplaceAllString(sql, schema+`$1`)
-
-	return sql
+	return rewriteSQL(sql, sqlSyntax{brackets: true}, nil, func(name string) string {
+		return schema + name
+	})
 }
@@ -5,8 +5,15 @@
 package datahelper
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"log"
+	"path/filepath"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -180,3 +187,1140 @@ func TestGetSequenceX(t *testing.T) {
 		}
 	}
 }
+
+// newSQLiteConfig creates a configuration to a temporary SQLite database
+func newSQLiteConfig(t *testing.T) *cfg.Configuration {
+	t.Helper()
+
+	id := `DEFAULT`
+	return &cfg.Configuration{
+		DefaultDatabaseID: &id,
+		Databases: &[]cfg.DatabaseInfo{
+			{
+				ID:                   id,
+				ConnectionString:     filepath.Join(t.TempDir(), `test.db`),
+				DriverName:           `sqlite3`,
+				StorageType:          `FILE`,
+				ParameterPlaceholder: `?`,
+			},
+		},
+	}
+}
+
+// newSQLiteHelper creates a connected DataHelper to a temporary SQLite database
+func newSQLiteHelper(t *testing.T) *DataHelper {
+	t.Helper()
+
+	db := NewDataHelper(newSQLiteConfig(t))
+	if _, err := db.Connect(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	t.Cleanup(func() { db.Disconnect(false) })
+
+	if _, err := db.Exec(`CREATE TABLE USERACCOUNT (UserKey INTEGER PRIMARY KEY, UserName TEXT NOT NULL, Active INTEGER, GMT REAL, DateLastLoggedIn DATETIME);`); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	return db
+}
+
+func TestGetDataContextCancelled(t *testing.T) {
+	db := newSQLiteHelper(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := db.GetDataContext(ctx, `SELECT UserKey FROM USERACCOUNT;`); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	if db.AllQueryOK {
+		t.Fatal("AllQueryOK should be false after a cancelled query")
+	}
+
+	if _, err := db.ExecContext(context.Background(), `INSERT INTO USERACCOUNT (UserKey, UserName) VALUES (?, ?);`, 1, `admin`); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	sr, err := db.GetRowContext(context.Background(), []string{`UserName`}, `USERACCOUNT WHERE UserKey = ?`, 1)
+	if err != nil || !sr.HasResult || sr.Row.ValueStringOrd(0) != `admin` {
+		t.Fatalf("unexpected row %v, %v", sr, err)
+	}
+}
+
+func TestSelectStruct(t *testing.T) {
+	db := newSQLiteHelper(t)
+
+	if _, err := db.Exec(`INSERT INTO USERACCOUNT (UserKey, UserName, Active, GMT, DateLastLoggedIn) VALUES (?, ?, ?, ?, ?);`, 2, `admin`, true, 8.0, `2023-06-20 10:30:00`); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	type Audit struct {
+		DateLastLoggedIn time.Time
+	}
+
+	type UserAccount struct {
+		Audit
+		Key      int64  `db:"UserKey"`
+		UserName string `db:"username"`
+		Active   bool   // SQLite returns int64
+		GMT      *float64
+		Ignored  string `db:"-"`
+	}
+
+	uas, err := Select[UserAccount](db, `SELECT UserKey, UserName, Active, GMT, DateLastLoggedIn, 'x' AS Ignored FROM USERACCOUNT;`)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if len(uas) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(uas))
+	}
+
+	ua := uas[0]
+	if ua.Key != 2 || ua.UserName != `admin` || !ua.Active || ua.GMT == nil || *ua.GMT != 8 || ua.Ignored != "" {
+		t.Fatalf("unexpected mapping %+v", ua)
+	}
+
+	if ua.DateLastLoggedIn.Year() != 2023 || ua.DateLastLoggedIn.Hour() != 10 {
+		t.Fatalf("unexpected time %v", ua.DateLastLoggedIn)
+	}
+
+	name, err := Get[string](db, `SELECT UserName FROM USERACCOUNT WHERE UserKey = ?;`, 2)
+	if err != nil || name != `admin` {
+		t.Fatalf("unexpected result %q, %v", name, err)
+	}
+
+	if _, err = Get[UserAccount](db, `SELECT UserKey FROM USERACCOUNT WHERE UserKey = ?;`, 99); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+
+	// an embedded pointer to an unexported struct is skipped
+	type audit struct {
+		DateLastLoggedIn time.Time
+	}
+
+	type Login struct {
+		*audit
+		UserName string
+	}
+
+	login, err := Get[Login](db, `SELECT UserName, DateLastLoggedIn FROM USERACCOUNT WHERE UserKey = ?;`, 2)
+	if err != nil || login.UserName != `admin` || login.audit != nil {
+		t.Fatalf("unexpected mapping %+v, %v", login, err)
+	}
+}
+
+func TestSavepoints(t *testing.T) {
+	db := newSQLiteHelper(t)
+
+	count := func() int64 {
+		sr, err := db.GetRow([]string{`COUNT(*)`}, `USERACCOUNT`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		return sr.Row.ValueInt64Ord(0)
+	}
+
+	if _, err := db.Begin(false); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	db.Exec(`INSERT INTO USERACCOUNT (UserKey, UserName) VALUES (1, 'one');`)
+	if err := db.Mark(`outer`); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	db.Exec(`INSERT INTO USERACCOUNT (UserKey, UserName) VALUES (2, 'two');`)
+	if err := db.Mark(`inner`); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	db.Exec(`INSERT INTO USERACCOUNT (UserKey, UserName) VALUES (3, 'three');`)
+
+	if err := db.Discard(`outer`); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if n := count(); n != 1 {
+		t.Fatalf("expected 1 row after discard, got %d", n)
+	}
+
+	var upe *UnknownPointError
+	if err := db.Discard(`inner`); !errors.As(err, &upe) || upe.PointID != `inner` {
+		t.Fatalf("expected UnknownPointError, got %v", err)
+	}
+
+	db.Exec(`INSERT INTO USERACCOUNT (UserKey, UserName) VALUES (4, 'four');`)
+	if err := db.Release(`outer`); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if len(db.Savepoints()) != 0 {
+		t.Fatalf("unexpected save points %v", db.Savepoints())
+	}
+
+	if err := db.Mark(`bad name;`); err == nil {
+		t.Fatal("expected an error for an invalid point id")
+	}
+
+	if err := db.Commit(false); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if n := count(); n != 2 {
+		t.Fatalf("expected 2 rows after commit, got %d", n)
+	}
+}
+
+func TestInTransaction(t *testing.T) {
+	db := newSQLiteHelper(t)
+	ctx := context.Background()
+
+	errFail := errors.New("fail")
+
+	err := db.InTransaction(ctx, nil, func(tx *DataHelper) error {
+		if _, err := tx.Exec(`INSERT INTO USERACCOUNT (UserKey, UserName) VALUES (1, 'one');`); err != nil {
+			return err
+		}
+
+		// nested work is rolled back to its save point only
+		nerr := tx.InTransaction(ctx, nil, func(tx *DataHelper) error {
+			tx.Exec(`INSERT INTO USERACCOUNT (UserKey, UserName) VALUES (2, 'two');`)
+			return errFail
+		})
+		if !errors.Is(nerr, errFail) {
+			t.Errorf("expected the nested error, got %v", nerr)
+		}
+
+		return tx.InTransaction(ctx, nil, func(tx *DataHelper) error {
+			_, err := tx.Exec(`INSERT INTO USERACCOUNT (UserKey, UserName) VALUES (3, 'three');`)
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if db.IsInTransaction() {
+		t.Fatal("transaction was not committed")
+	}
+
+	keys, _ := Select[int](db, `SELECT UserKey FROM USERACCOUNT ORDER BY UserKey;`)
+	if len(keys) != 2 || keys[0] != 1 || keys[1] != 3 {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected the panic to be raised again")
+			}
+		}()
+
+		db.InTransaction(ctx, nil, func(tx *DataHelper) error {
+			tx.Exec(`DELETE FROM USERACCOUNT;`)
+			panic("boom")
+		})
+	}()
+
+	if db.IsInTransaction() {
+		t.Fatal("transaction was not rolled back after a panic")
+	}
+
+	if keys, _ = Select[int](db, `SELECT UserKey FROM USERACCOUNT;`); len(keys) != 2 {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+}
+
+func TestRetryTransaction(t *testing.T) {
+	db := newSQLiteHelper(t)
+
+	runs := 0
+	attempts, err := db.RetryTransaction(context.Background(), nil, RetryOptions{MaxAttempts: 5, BaseDelay: time.Millisecond}, func(tx *DataHelper) error {
+		runs++
+		if _, err := tx.Exec(`INSERT INTO USERACCOUNT (UserKey, UserName) VALUES (?, 'user');`, runs); err != nil {
+			return err
+		}
+		if runs < 3 {
+			return fakeSQLStateError(`40001`)
+		}
+		return nil
+	})
+
+	if err != nil || attempts != 3 {
+		t.Fatalf("unexpected result %d, %v", attempts, err)
+	}
+
+	// only the last run is committed
+	keys, _ := Select[int](db, `SELECT UserKey FROM USERACCOUNT;`)
+	if len(keys) != 1 || keys[0] != 3 {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+
+	attempts, err = db.RetryTransaction(context.Background(), nil, RetryOptions{MaxAttempts: 2, BaseDelay: time.Millisecond}, func(tx *DataHelper) error {
+		return fakeSQLServerError(1205)
+	})
+	if attempts != 2 || !IsRetryable(err) {
+		t.Fatalf("unexpected result %d, %v", attempts, err)
+	}
+}
+
+func TestQueryErrorClassification(t *testing.T) {
+	db := newSQLiteHelper(t)
+
+	db.Exec(`INSERT INTO USERACCOUNT (UserKey, UserName) VALUES (1, 'one');`)
+	_, err := db.Exec(`INSERT INTO USERACCOUNT (UserKey, UserName) VALUES (?, ?);`, 1, `again`)
+
+	var qe *QueryError
+	if !errors.As(err, &qe) || qe.Op != `Exec` || qe.ArgCount != 2 {
+		t.Fatalf("expected a QueryError, got %v", err)
+	}
+
+	if !IsUniqueViolation(err) || IsForeignKeyViolation(err) || IsRetryable(err) {
+		t.Fatalf("unexpected classification of %v", err)
+	}
+
+	if db.AllQueryOK || len(db.QueryErrors) != 1 || !IsUniqueViolation(db.QueryErrors[0]) {
+		t.Fatalf("unexpected error state %v", db.QueryErrors)
+	}
+
+	if _, err = db.GetRow([]string{`UserKey`}, ``); !errors.Is(err, ErrNoTableName) {
+		t.Fatalf("expected ErrNoTableName, got %v", err)
+	}
+
+	if err = db.Commit(false); !errors.Is(err, ErrNoTransaction) {
+		t.Fatalf("expected ErrNoTransaction, got %v", err)
+	}
+
+	if _, err = NewDataHelper(&cfg.Configuration{}).GetData(`SELECT 1`); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("expected ErrNotConnected, got %v", err)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	conn, err := Open(newSQLiteConfig(t))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer conn.Close()
+
+	// SQLite allows a single writer
+	conn.DB().SetMaxOpenConns(1)
+
+	s := conn.Session()
+	if _, err = s.Exec(`CREATE TABLE USERACCOUNT (UserKey INTEGER PRIMARY KEY, UserName TEXT NOT NULL);`); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	s.Disconnect(false)
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		wg.Add(1)
+		go func(key int) {
+			defer wg.Done()
+
+			s := conn.Session()
+			defer s.Close()
+
+			err := s.InTransaction(context.Background(), nil, func(tx *DataHelper) error {
+				_, err := tx.Exec(`INSERT INTO USERACCOUNT (UserKey, UserName) VALUES (?, ?);`, key, fmt.Sprint(`user`, key))
+				return err
+			})
+			if err != nil {
+				t.Errorf("Error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// a closed session leaves the pool open for the others
+	n, err := Get[int](conn.Session().DataHelper, `SELECT COUNT(*) FROM USERACCOUNT;`)
+	if err != nil || n != 10 {
+		t.Fatalf("unexpected count %d, %v", n, err)
+	}
+}
+
+func TestRegistrySharesPools(t *testing.T) {
+	config := newSQLiteConfig(t)
+
+	db1, _, err := NewConnected(nil, config)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	db2 := NewDataHelper(config)
+	if _, err = db2.Connect(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if db1.db != db2.db {
+		t.Fatal("helpers to the same database should share a pool")
+	}
+
+	// disconnecting a helper leaves the pool to the others
+	db1.Disconnect(false)
+	if _, err = db2.Exec(`CREATE TABLE T (ID INTEGER);`); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// a helper sharing the pool keeps the database information of its own configuration
+	other := newSQLiteConfig(t)
+	(*other.Databases)[0].ConnectionString = (*config.Databases)[0].ConnectionString
+	(*other.Databases)[0].Schema = `main`
+
+	db3 := NewDataHelper(other)
+	if _, err = db3.Connect(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if db3.db != db2.db || db3.CurrentDatabaseInfo.Schema != `main` || db2.CurrentDatabaseInfo.Schema != `` {
+		t.Fatalf("unexpected schemas %q and %q", db3.CurrentDatabaseInfo.Schema, db2.CurrentDatabaseInfo.Schema)
+	}
+	if db3.Settings.GetDatabaseInfo(`DEFAULT`).Schema != `main` {
+		t.Fatal("expected the settings of the own configuration")
+	}
+	if _, err = db3.Exec(`INSERT INTO {T} (ID) VALUES (1);`); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	reg := NewRegistry()
+	s, err := reg.Session(config)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if s.db == db2.db {
+		t.Fatal("registries should not share pools")
+	}
+
+	// closing a connection of the registry leaves the shared pool open
+	conn, err := reg.Connection(config)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err = conn.Close(); err != nil || s.db.Ping() != nil {
+		t.Fatalf("expected the pool to stay open, %v", err)
+	}
+
+	if err = reg.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if _, err = s.Exec(`INSERT INTO T (ID) VALUES (2);`); err == nil {
+		t.Fatal("expected an error after shutdown")
+	}
+}
+
+func TestDisconnectKeepsPool(t *testing.T) {
+	config := newSQLiteConfig(t)
+
+	db, _, err := NewConnected(nil, config)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	pool := db.db
+	if err = db.Disconnect(false); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// the pool stays open for the next helper to the database
+	if db, _, err = NewConnected(nil, config); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if db.db != pool || pool.Ping() != nil {
+		t.Fatal("expected the next helper to reuse the pool")
+	}
+	db.Disconnect(false)
+
+	// closing the pool early opens a new one for the next helper
+	if err = DefaultRegistry().ClosePool(config); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err = pool.Ping(); err == nil {
+		t.Fatal("expected the pool to be closed")
+	}
+	if db, _, err = NewConnected(nil, config); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if db.db == pool || db.db.Ping() != nil {
+		t.Fatal("expected a new pool")
+	}
+	db.Disconnect(false)
+}
+
+// blockingDriver is a database driver whose connections open when its channel is closed
+type blockingDriver chan struct{}
+
+func (d blockingDriver) Open(string) (driver.Conn, error) {
+	<-d
+	return nil, errors.New("blocking: no database")
+}
+
+func TestRegistryOpensConcurrently(t *testing.T) {
+	reg := NewRegistry()
+	defer reg.Shutdown(context.Background())
+
+	// drivers could not be unregistered, so every run registers its own
+	unblock := make(blockingDriver)
+	name := fmt.Sprintf(`blocking_%p`, unblock)
+	sql.Register(name, unblock)
+
+	ping := true
+	id := `SLOW`
+	slow := &cfg.Configuration{
+		DefaultDatabaseID: &id,
+		Databases: &[]cfg.DatabaseInfo{
+			{ID: id, ConnectionString: `slow`, DriverName: name, StorageType: `SERVER`, Ping: &ping},
+		},
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := reg.Connection(slow)
+		done <- err
+	}()
+
+	// a database that is slow to open does not hold up the others
+	opened := make(chan error, 1)
+	go func() {
+		_, err := reg.Connection(newSQLiteConfig(t))
+		opened <- err
+	}()
+
+	select {
+	case err := <-opened:
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("opening a pool waited for another database")
+	}
+
+	close(unblock)
+	if err := <-done; err == nil {
+		t.Fatal("expected the ping error")
+	}
+}
+
+func TestQueryBuilder(t *testing.T) {
+	db := newSQLiteHelper(t)
+
+	_, err := db.InsertInto(`{USERACCOUNT}`).
+		Columns(`UserKey`, `UserName`, `Active`).
+		Values(1, `admin`, 1).
+		Values(2, `guest`, 0).
+		Values(3, `staff`, 1).
+		Exec()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if _, err = db.UpdateTable(`{USERACCOUNT}`).Set(`Active`, 1).Where(`UserKey = ?`, 2).Exec(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if _, err = db.DeleteFrom(`{USERACCOUNT}`).Where(`UserKey IN (?)`, []int{3}).Exec(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	dt, err := db.Select(`UserKey`, `UserName`).
+		From(`{USERACCOUNT}`).
+		Where(`Active = ?`, 1).
+		OrderBy(`UserKey DESC`).
+		Limit(1).
+		GetData()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if dt.RowCount != 1 || dt.Rows[0].ValueStringOrd(1) != `guest` {
+		t.Fatalf("unexpected result %+v", dt.Rows)
+	}
+}
+
+func TestWriteHelpers(t *testing.T) {
+	db := newSQLiteHelper(t)
+
+	type UserAccount struct {
+		UserKey   int64  `db:"UserKey"`
+		UserName  string `db:"UserName"`
+		Active    bool
+		LastLogin string `db:"DateLastLoggedIn,readonly"`
+		Notes     string `db:"-"`
+	}
+
+	if _, err := db.Insert(`{USERACCOUNT}`, UserAccount{UserKey: 1, UserName: `admin`, Active: true, LastLogin: `x`, Notes: `y`}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if _, err := db.Insert(`{USERACCOUNT}`, map[string]interface{}{`UserKey`: 2, `UserName`: `guest`}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if _, err := db.Update(`{USERACCOUNT}`, map[string]interface{}{`UserName`: `visitor`}, `UserKey = ?`, 2); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	res, err := db.Delete(`{USERACCOUNT}`, `UserKey = ?`, 1)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.Fatalf("expected 1 deleted row, got %d", n)
+	}
+
+	name, err := Get[string](db, `SELECT UserName FROM USERACCOUNT WHERE UserKey = ?;`, 2)
+	if err != nil || name != `visitor` {
+		t.Fatalf("unexpected result %q, %v", name, err)
+	}
+
+	if _, err = db.Insert(`{USERACCOUNT}`, 5); err == nil {
+		t.Fatal("expected an error for scalar values")
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	db := newSQLiteHelper(t)
+
+	for _, name := range []string{`admin`, `root`} {
+		res, err := db.Upsert(`{USERACCOUNT}`, []string{`UserKey`}, map[string]interface{}{`UserKey`: 1, `UserName`: name})
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if res != UpsertUnknown {
+			t.Fatalf("expected SQLite to report %v, got %v", UpsertUnknown, res)
+		}
+	}
+
+	name, err := Get[string](db, `SELECT UserName FROM USERACCOUNT WHERE UserKey = ?;`, 1)
+	if err != nil || name != `root` {
+		t.Fatalf("unexpected result %q, %v", name, err)
+	}
+
+	if _, err = db.Upsert(`{USERACCOUNT}`, []string{`UserKey`}, map[string]interface{}{`UserName`: `x`}); !errors.Is(err, ErrNoColumn) {
+		t.Fatalf("expected ErrNoColumn, got %v", err)
+	}
+}
+
+func TestInsertReturning(t *testing.T) {
+	db := newSQLiteHelper(t)
+
+	r, err := db.InsertReturning(`INSERT INTO {USERACCOUNT} (UserName) VALUES (?);`, []string{`UserKey`, `UserName`}, `admin`)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if r.ValueInt64Ord(0) != 1 || r.ValueStringOrd(1) != `admin` {
+		t.Fatalf("unexpected row %+v", r.Cells)
+	}
+}
+
+func TestBulkInsert(t *testing.T) {
+	db := newSQLiteHelper(t)
+
+	rows := make([][]interface{}, 250)
+	for i := range rows {
+		rows[i] = []interface{}{i + 1, `user` + strconv.Itoa(i+1)}
+	}
+
+	var calls []int64
+	n, err := db.BulkInsertContext(context.Background(), `{USERACCOUNT}`, []string{`UserKey`, `UserName`}, rows, BulkOptions{
+		BatchSize: 100,
+		Progress:  func(inserted int64) { calls = append(calls, inserted) },
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if n != 250 || len(calls) != 3 || calls[2] != 250 {
+		t.Fatalf("unexpected result %d, progress %v", n, calls)
+	}
+
+	type UserAccount struct {
+		UserKey  int64
+		UserName string
+		Active   bool
+	}
+
+	users := []UserAccount{{UserKey: 251, UserName: `a`}, {UserKey: 252, UserName: `b`, Active: true}}
+	if n, err = db.BulkInsert(`{USERACCOUNT}`, nil, users); err != nil || n != 2 {
+		t.Fatalf("unexpected result %d, %v", n, err)
+	}
+
+	dt, err := db.GetData(`SELECT UserKey + 1000 AS UserKey, UserName FROM USERACCOUNT WHERE UserKey <= 10;`)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if n, err = db.BulkInsertContext(context.Background(), `{USERACCOUNT}`, nil, dt, BulkOptions{BatchSize: 1}); err != nil || n != 10 {
+		t.Fatalf("unexpected result %d, %v", n, err)
+	}
+
+	count, err := Get[int64](db, `SELECT COUNT(*) FROM USERACCOUNT;`)
+	if err != nil || count != 262 {
+		t.Fatalf("unexpected count %d, %v", count, err)
+	}
+
+	// a failing row rolls back the whole insert
+	if _, err = db.BulkInsert(`{USERACCOUNT}`, []string{`UserKey`, `UserName`}, [][]interface{}{{300, `x`}, {1, `dup`}}); err == nil {
+		t.Fatal("expected a duplicate key error")
+	}
+	if count, _ = Get[int64](db, `SELECT COUNT(*) FROM USERACCOUNT;`); count != 262 {
+		t.Fatalf("expected the insert to be rolled back, got %d rows", count)
+	}
+}
+
+func TestGetPage(t *testing.T) {
+	db := newSQLiteHelper(t)
+
+	rows := make([][]interface{}, 25)
+	for i := range rows {
+		rows[i] = []interface{}{i + 1, `user` + strconv.Itoa(i+1), i % 2}
+	}
+	if _, err := db.BulkInsert(`{USERACCOUNT}`, []string{`UserKey`, `UserName`, `Active`}, rows); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	query := `SELECT UserKey, UserName FROM USERACCOUNT WHERE Active = ?`
+
+	p, err := db.GetPage(query, PageRequest{Page: 2, Size: 5, OrderBy: []string{`UserKey`}}, 1)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if p.Total != 12 || !p.HasNext || p.Data.RowCount != 5 || p.Data.Rows[0].ValueInt64Ord(0) != 12 {
+		t.Fatalf("unexpected page %+v", p)
+	}
+
+	p, err = db.GetPage(query, PageRequest{Page: 3, Size: 5, OrderBy: []string{`UserKey`}}, 1)
+	if err != nil || p.HasNext || p.Data.RowCount != 2 {
+		t.Fatalf("unexpected last page %+v, %v", p, err)
+	}
+
+	var keys []int64
+	req := PageRequest{Size: 5, OrderBy: []string{`UserKey DESC`}, Keyset: true}
+	for {
+		p, err = db.GetPage(query, req, 1)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if p.Total != -1 {
+			t.Fatalf("keyset pages should not count, got %d", p.Total)
+		}
+		for _, r := range p.Data.Rows {
+			keys = append(keys, r.ValueInt64Ord(0))
+		}
+		if !p.HasNext {
+			break
+		}
+		req.After = p.LastKey
+	}
+
+	if len(keys) != 12 || keys[0] != 24 || keys[11] != 2 {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+
+	if _, err = db.GetPage(query, PageRequest{}, 1); !errors.Is(err, ErrNoPageSize) {
+		t.Fatalf("expected ErrNoPageSize, got %v", err)
+	}
+}
+
+func TestRead(t *testing.T) {
+	db := newSQLiteHelper(t)
+
+	rows := [][]interface{}{{1, `admin`, 1}, {2, `guest`, 0}, {3, `staff`, 1}}
+	if _, err := db.BulkInsert(`{USERACCOUNT}`, []string{`UserKey`, `UserName`, `Active`}, rows); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	RegisterTable(TableDef{
+		Name:        `{USERACCOUNT}`,
+		KeyColumn:   `UserKey`,
+		CodeColumn:  `UserName`,
+		FormColumns: []string{`UserKey`, `UserName`},
+		OrderBy:     []string{`UserName DESC`},
+	})
+
+	def, ok := GetTable(`useraccount`)
+	if !ok {
+		t.Fatal("registered table was not found")
+	}
+
+	dt, err := db.Read(def, READALL, nil)
+	if err != nil || dt.RowCount != 3 || dt.Rows[0].ValueStringOrd(1) != `staff` {
+		t.Fatalf("unexpected READALL %+v, %v", dt, err)
+	}
+
+	dt, err = db.Read(def, READBYKEY, 2)
+	if err != nil || dt.RowCount != 1 || dt.Rows[0].ValueStringOrd(1) != `guest` {
+		t.Fatalf("unexpected READBYKEY %+v, %v", dt, err)
+	}
+
+	dt, err = db.ReadTable(`{USERACCOUNT}`, READBYCODE, `staff`)
+	if err != nil || dt.RowCount != 1 || dt.Rows[0].ValueInt64Ord(0) != 3 {
+		t.Fatalf("unexpected READBYCODE %+v, %v", dt, err)
+	}
+
+	dt, err = db.Read(def, READFORFORM, nil)
+	if err != nil || dt.RowCount != 3 || dt.ColumnCount != 2 {
+		t.Fatalf("unexpected READFORFORM %+v, %v", dt, err)
+	}
+
+	if _, err = db.Read(def, ReadType(`other`), nil); !errors.Is(err, ErrUnknownReadType) {
+		t.Fatalf("expected ErrUnknownReadType, got %v", err)
+	}
+
+	if _, err = db.ReadTable(`NOTREGISTERED`, READALL, nil); !errors.Is(err, ErrUnknownTable) {
+		t.Fatalf("expected ErrUnknownTable, got %v", err)
+	}
+}
+
+func TestSchemaIntrospection(t *testing.T) {
+	db := newSQLiteHelper(t)
+
+	for _, q := range []string{
+		`CREATE TABLE ROLE (RoleCode VARCHAR(20) NOT NULL, Rate DECIMAL(10, 2) DEFAULT 0, PRIMARY KEY (RoleCode));`,
+		`CREATE TABLE USERROLE (UserKey INTEGER NOT NULL REFERENCES USERACCOUNT (UserKey), RoleCode VARCHAR(20) NOT NULL REFERENCES ROLE (RoleCode), PRIMARY KEY (UserKey, RoleCode));`,
+		`CREATE UNIQUE INDEX IX_USERACCOUNT_NAME ON USERACCOUNT (UserName);`,
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+
+	tables, err := db.Tables()
+	if err != nil || len(tables) != 3 || tables[0] != `ROLE` {
+		t.Fatalf("unexpected tables %v, %v", tables, err)
+	}
+
+	cols, err := db.Columns(`{USERACCOUNT}`)
+	if err != nil || len(cols) != 5 {
+		t.Fatalf("unexpected columns %+v, %v", cols, err)
+	}
+	if !cols[0].Identity || cols[0].Nullable || cols[1].Nullable || !cols[2].Nullable {
+		t.Fatalf("unexpected column metadata %+v", cols)
+	}
+
+	cols, err = db.Columns(`ROLE`)
+	if err != nil || cols[0].DBType != `VARCHAR` || cols[0].Length != 20 || cols[0].Identity ||
+		cols[1].Precision != 10 || cols[1].Scale != 2 || cols[1].Default == nil || *cols[1].Default != `0` {
+		t.Fatalf("unexpected columns %+v, %v", cols, err)
+	}
+
+	pk, err := db.PrimaryKey(`USERROLE`)
+	if err != nil || len(pk) != 2 || pk[0] != `UserKey` || pk[1] != `RoleCode` {
+		t.Fatalf("unexpected primary key %v, %v", pk, err)
+	}
+
+	fks, err := db.ForeignKeys(`USERROLE`)
+	if err != nil || len(fks) != 2 {
+		t.Fatalf("unexpected foreign keys %+v, %v", fks, err)
+	}
+	for _, fk := range fks {
+		if len(fk.Columns) != 1 || fk.Columns[0] != fk.RefColumns[0] {
+			t.Fatalf("unexpected foreign key %+v", fk)
+		}
+	}
+
+	idxs, err := db.Indexes(`USERACCOUNT`)
+	if err != nil || len(idxs) != 1 || idxs[0].Name != `IX_USERACCOUNT_NAME` || !idxs[0].Unique || idxs[0].Columns[0] != `UserName` {
+		t.Fatalf("unexpected indexes %+v, %v", idxs, err)
+	}
+}
+
+func TestNativeSequence(t *testing.T) {
+	db := newSQLiteHelper(t)
+
+	for i := 1; i <= 3; i++ {
+		key, err := db.GetSequence(`USERACCOUNT`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if key != strconv.Itoa(i) {
+			t.Fatalf("expected %d, got %s", i, key)
+		}
+	}
+
+	// sequences are counted apart and work inside a transaction
+	err := db.InTransaction(context.Background(), nil, func(tx *DataHelper) error {
+		key, err := tx.GetSequence(`ROLE`)
+		if err == nil && key != `1` {
+			err = fmt.Errorf("expected 1, got %s", key)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if _, err = db.GetSequence(`USER'; DROP TABLE USERACCOUNT; --`); !errors.Is(err, ErrInvalidSequenceName) {
+		t.Fatalf("expected ErrInvalidSequenceName, got %v", err)
+	}
+
+	// configured queries override the native sequence
+	config := newSQLiteConfig(t)
+	(*config.Databases)[0].SequenceGenerator = &cfg.SequenceGeneratorInfo{
+		UpsertQuery:     `CREATE TABLE IF NOT EXISTS SEQ (n INTEGER);`,
+		ResultQuery:     `SELECT 42 AS n WHERE '{SequenceName}' = 'USERACCOUNT';`,
+		NamePlaceHolder: `{SequenceName}`,
+	}
+	ovr := NewDataHelper(config)
+	if _, err = ovr.Connect(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer ovr.Disconnect(false)
+
+	if key, err := ovr.GetSequence(`USERACCOUNT`); err != nil || key != `42` {
+		t.Fatalf("expected the configured sequence, got %q, %v", key, err)
+	}
+}
+
+func TestSequenceNameBinding(t *testing.T) {
+	q, args, err := bindSequenceName(`INSERT INTO KEYGENERATOR(SequenceName, SequenceNo) values('{SequenceName}', 1) ON CONFLICT (SequenceName) DO UPDATE SET SequenceNo=SequenceNo+1 WHERE {SequenceName} <> N'{SequenceName}' AND 'it''s' <> '';`, `{SequenceName}`, `USERACCOUNT`)
+	if want := `INSERT INTO KEYGENERATOR(SequenceName, SequenceNo) values(?, 1) ON CONFLICT (SequenceName) DO UPDATE SET SequenceNo=SequenceNo+1 WHERE ? <> ? AND 'it''s' <> '';`; err != nil || q != want || len(args) != 3 {
+		t.Fatalf("got %q, %v, %v", q, args, err)
+	}
+
+	// a placeholder in a name or in a longer string could not be bound
+	for _, bad := range []string{
+		`SELECT nextval('seq_{SequenceName}');`,
+		`SELECT NEXT VALUE FOR seq_{SequenceName};`,
+		`SELECT SequenceNo FROM KEYGENERATOR WHERE SequenceName = 'PFX_{SequenceName}';`,
+		`SELECT SequenceNo FROM KEYGENERATOR WHERE SequenceName = '{SequenceName}_X';`,
+		`SELECT SequenceNo FROM dbo.{SequenceName};`,
+	} {
+		if _, _, err := bindSequenceName(bad, `{SequenceName}`, `USERACCOUNT`); !errors.Is(err, ErrSequencePlaceholder) {
+			t.Errorf("%s: expected ErrSequencePlaceholder, got %v", bad, err)
+		}
+	}
+
+	config := newSQLiteConfig(t)
+	(*config.Databases)[0].SequenceGenerator = &cfg.SequenceGeneratorInfo{
+		UpsertQuery:     `INSERT INTO KEYGENERATOR(SequenceName, SequenceNo) values('{SequenceName}', 1) ON CONFLICT (SequenceName) DO UPDATE SET SequenceNo=SequenceNo+1;`,
+		ResultQuery:     `SELECT SequenceNo FROM KEYGENERATOR WHERE SequenceName='{SequenceName}';`,
+		NamePlaceHolder: `{SequenceName}`,
+	}
+
+	db := NewDataHelper(config)
+	if _, err := db.Connect(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer db.Disconnect(false)
+
+	if _, err := db.Exec(`CREATE TABLE KEYGENERATOR (SequenceName VARCHAR(85) PRIMARY KEY, SequenceNo INT NOT NULL);`); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	for i := int64(1); i <= 2; i++ {
+		sq, err := db.GetSequenceInt64(`USERACCOUNT`)
+		if err != nil || sq != i {
+			t.Fatalf("expected %d, got %d, %v", i, sq, err)
+		}
+	}
+
+	if _, err := db.GetSequenceInt64(`X', 1); DROP TABLE KEYGENERATOR; --`); !errors.Is(err, ErrInvalidSequenceName) {
+		t.Fatalf("expected ErrInvalidSequenceName, got %v", err)
+	}
+
+	// a configured query with a placeholder inside a string is not run
+	(*config.Databases)[0].SequenceGenerator.ResultQuery = `SELECT SequenceNo FROM KEYGENERATOR WHERE SequenceName = 'PFX_{SequenceName}';`
+	bad := NewDataHelper(config)
+	if _, err := bad.Connect(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer bad.Disconnect(false)
+
+	if _, err := bad.GetSequenceInt64(`USERACCOUNT`); !errors.Is(err, ErrSequencePlaceholder) {
+		t.Fatalf("expected ErrSequencePlaceholder, got %v", err)
+	}
+	if sq, _ := Get[int64](db, `SELECT SequenceNo FROM KEYGENERATOR WHERE SequenceName = ?;`, `USERACCOUNT`); sq != 2 {
+		t.Fatalf("expected the upsert not to run, got %d", sq)
+	}
+}
+
+func TestSequenceBlocks(t *testing.T) {
+	config := newSQLiteConfig(t)
+
+	SetSequenceBlockSize(`INVOICE`, 10)
+	defer SetSequenceBlockSize(`INVOICE`, 0)
+
+	var (
+		mu   sync.Mutex
+		seen = map[int64]bool{}
+		wg   sync.WaitGroup
+	)
+
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			db := NewDataHelper(config)
+			if _, err := db.Connect(); err != nil {
+				t.Errorf("Error: %v", err)
+				return
+			}
+			defer db.Disconnect(false)
+
+			for i := 0; i < 25; i++ {
+				sq, err := db.GetSequenceInt64(`INVOICE`)
+				if err != nil {
+					t.Errorf("Error: %v", err)
+					return
+				}
+				mu.Lock()
+				if seen[sq] {
+					t.Errorf("duplicate number %d", sq)
+				}
+				seen[sq] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	db := NewDataHelper(config)
+	if _, err := db.Connect(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer db.Disconnect(false)
+
+	// 200 numbers were handed out in 20 blocks
+	counter, err := Get[int64](db, `SELECT SequenceNo FROM KEYGENERATOR WHERE SequenceName = ?;`, `INVOICE`)
+	if err != nil || counter != 200 || len(seen) != 200 || !seen[1] || !seen[200] {
+		t.Fatalf("unexpected counter %d with %d numbers, %v", counter, len(seen), err)
+	}
+
+	// in a transaction the number is taken from the table in the transaction, and rolled back with it
+	db.InTransaction(context.Background(), nil, func(tx *DataHelper) error {
+		sq, err := tx.GetSequenceInt64(`INVOICE`)
+		if err != nil || sq != 201 {
+			t.Errorf("expected 201, got %d, %v", sq, err)
+		}
+		return errors.New("rollback")
+	})
+
+	if sq, err := db.GetSequenceInt64(`INVOICE`); err != nil || sq != 201 {
+		t.Fatalf("expected 201, got %d, %v", sq, err)
+	}
+	if sq, err := db.GetSequenceInt64(`INVOICE`); err != nil || sq != 202 {
+		t.Fatalf("expected 202 from the block, got %d, %v", sq, err)
+	}
+}
+
+func TestSequenceBlockInTransaction(t *testing.T) {
+	db := newSQLiteHelper(t)
+	db.db.SetMaxOpenConns(1)
+
+	SetSequenceBlockSize(`RECEIPT`, 5)
+	defer SetSequenceBlockSize(`RECEIPT`, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if _, err := db.Begin(false); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// the transaction holds the write lock and the only connection
+	if _, err := db.ExecContext(ctx, `INSERT INTO USERACCOUNT (UserKey, UserName) VALUES (1, 'admin');`); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	for i := int64(1); i <= 2; i++ {
+		sq, err := db.GetSequenceInt64Context(ctx, `RECEIPT`)
+		if err != nil || sq != i {
+			t.Fatalf("expected %d, got %d, %v", i, sq, err)
+		}
+	}
+
+	if err := db.Commit(false); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// the numbers taken in the transaction were committed, and a block follows them
+	if sq, err := db.GetSequenceInt64Context(ctx, `RECEIPT`); err != nil || sq != 3 {
+		t.Fatalf("expected 3, got %d, %v", sq, err)
+	}
+	if counter, _ := Get[int64](db, `SELECT SequenceNo FROM KEYGENERATOR WHERE SequenceName = ?;`, `RECEIPT`); counter != 7 {
+		t.Fatalf("expected a block up to 7, got %d", counter)
+	}
+}
+
+func TestFormattedSequence(t *testing.T) {
+	db := newSQLiteHelper(t)
+
+	SetSequenceReset(`PICKLIST`, ResetYearly)
+	defer SetSequenceReset(`PICKLIST`, ResetNever)
+
+	year := time.Now().Format(`2006`)
+	for i := 1; i <= 2; i++ {
+		no, err := db.GetFormattedSequence(`PICKLIST`, `PL-{yyyy}-{seq:4}`)
+		if err != nil || no != `PL-`+year+`-000`+strconv.Itoa(i) {
+			t.Fatalf("unexpected number %q, %v", no, err)
+		}
+	}
+
+	// the period counter is kept under its own key
+	counter, err := Get[int64](db, `SELECT SequenceNo FROM KEYGENERATOR WHERE SequenceName = ?;`, `PICKLIST_`+year)
+	if err != nil || counter != 2 {
+		t.Fatalf("unexpected counter %d, %v", counter, err)
+	}
+
+	// a bad format does not use a number
+	if _, err = db.GetFormattedSequence(`PICKLIST`, `PL-{week}`); !errors.Is(err, ErrInvalidSequenceFormat) {
+		t.Fatalf("expected ErrInvalidSequenceFormat, got %v", err)
+	}
+	if counter, _ = Get[int64](db, `SELECT SequenceNo FROM KEYGENERATOR WHERE SequenceName = ?;`, `PICKLIST_`+year); counter != 2 {
+		t.Fatalf("expected the counter to stay at 2, got %d", counter)
+	}
+}
+
+func TestInsertGeneratedKey(t *testing.T) {
+	db := newSQLiteHelper(t)
+
+	if _, err := db.Exec(`CREATE TABLE PICKLIST (PickListID TEXT PRIMARY KEY, PickListNo TEXT NOT NULL);
+		CREATE TABLE SHIPMENT (ShipKey INTEGER PRIMARY KEY, ShipNo TEXT NOT NULL);`); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	RegisterTable(TableDef{Name: `{PICKLIST}`, KeyColumn: `PickListID`, IDGenerator: &ULIDGenerator{}})
+	RegisterTable(TableDef{Name: `{SHIPMENT}`, KeyColumn: `ShipKey`, IDGenerator: SequenceGenerator{SequenceKey: `SHIPMENT`}})
+
+	type PickList struct {
+		PickListID *string `db:"PickListID"`
+		PickListNo string  `db:"PickListNo"`
+	}
+
+	pl := PickList{PickListNo: `PL-1`}
+	if _, err := db.Insert(`{PICKLIST}`, &pl); err != nil || pl.PickListID == nil || len(*pl.PickListID) != 26 {
+		t.Fatalf("expected a generated key, got %+v, %v", pl, err)
+	}
+
+	// a key already set is kept
+	id := `01ARZ3NDEKTSV4RRFFQ69G5FAV`
+	if _, err := db.Insert(`{PICKLIST}`, PickList{PickListID: &id, PickListNo: `PL-2`}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if n, _ := Get[int64](db, `SELECT COUNT(*) FROM PICKLIST WHERE PickListID = ?;`, id); n != 1 {
+		t.Fatalf("expected the key to be kept")
+	}
+
+	type Shipment struct {
+		ShipKey int    `db:"ShipKey,readonly"`
+		ShipNo  string `db:"ShipNo"`
+	}
+
+	for i := 1; i <= 2; i++ {
+		sh := Shipment{ShipNo: `S-` + strconv.Itoa(i)}
+		if _, err := db.Insert(`{SHIPMENT}`, &sh); err != nil || sh.ShipKey != i {
+			t.Fatalf("unexpected result %+v, %v", sh, err)
+		}
+	}
+
+	// a map without the key column gets one
+	if _, err := db.Insert(`{SHIPMENT}`, map[string]interface{}{`ShipNo`: `S-3`}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if n, _ := Get[int64](db, `SELECT MAX(ShipKey) FROM SHIPMENT;`); n != 3 {
+		t.Fatalf("expected the keys to come from the sequence, got %d", n)
+	}
+}
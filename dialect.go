@@ -0,0 +1,272 @@
+package datahelper
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Dialect - SQL dialect specifics of a database driver
+type Dialect interface {
+	Name() string                                             // Name of the dialect
+	RowLimiting() RowLimiting                                 // Row limiting keyword and its placement in a SELECT
+	LimitOffset(limit, offset int64) string                   // Clause appended to an ordered query to get a page of rows
+	Savepoint(name string) string                             // Statement to set a savepoint
+	RollbackToSavepoint(name string) string                   // Statement to roll back to a savepoint
+	ReleaseSavepoint(name string) string                      // Statement to release a savepoint. Empty if the database has no release.
+	QuoteIdentifier(name string) string                       // Quotes a table or column name
+	Placeholder() (char string, inSequence bool)              // Parameter placeholder and if it is numbered
+	Upsert(table string, keyColumns, columns []string) string // Insert or update statement with ? parameters in the order of the columns
+	LastInsertIDQuery() string                                // Query to get the last generated identity. Empty if the driver supports sql.Result.LastInsertId
+}
+
+var (
+	dialectMu sync.RWMutex
+	dialects  = map[string]Dialect{}
+)
+
+func init() {
+	RegisterDialect(`sqlserver`, sqlServerDialect{placeholder: `@p`, inSequence: true})
+	RegisterDialect(`mssql`, sqlServerDialect{placeholder: `?`})
+	RegisterDialect(`postgres`, postgresDialect{})
+	RegisterDialect(`pgx`, postgresDialect{})
+	RegisterDialect(`sqlite3`, sqliteDialect{})
+	RegisterDialect(`sqlite`, sqliteDialect{})
+	RegisterDialect(`mysql`, mysqlDialect{})
+}
+
+// RegisterDialect - registers a dialect for a driver name. A registered dialect replaces the previous one.
+func RegisterDialect(driverName string, d Dialect) {
+	dialectMu.Lock()
+	defer dialectMu.Unlock()
+
+	dialects[strings.ToLower(driverName)] = d
+}
+
+// GetDialect - gets the dialect registered for a driver name.
+// If none was registered, an ANSI SQL dialect is returned.
+func GetDialect(driverName string) Dialect {
+	dialectMu.RLock()
+	defer dialectMu.RUnlock()
+
+	if d, ok := dialects[strings.ToLower(driverName)]; ok {
+		return d
+	}
+
+	return ansiDialect{}
+}
+
+// ansiDialect is the standard SQL behavior the built-in dialects are based on
+type ansiDialect struct{}
+
+func (ansiDialect) Name() string { return `ansi` }
+
+func (ansiDialect) RowLimiting() RowLimiting {
+	return RowLimiting{
+		Keyword:   "LIMIT",
+		Placement: RowLimitingRear,
+	}
+}
+
+func (ansiDialect) LimitOffset(limit, offset int64) string {
+	return `LIMIT ` + strconv.FormatInt(limit, 10) + ` OFFSET ` + strconv.FormatInt(offset, 10)
+}
+
+func (ansiDialect) Savepoint(name string) string {
+	return `SAVEPOINT ` + name
+}
+
+func (ansiDialect) RollbackToSavepoint(name string) string {
+	return `ROLLBACK TO SAVEPOINT ` + name
+}
+
+func (ansiDialect) ReleaseSavepoint(name string) string {
+	return `RELEASE SAVEPOINT ` + name
+}
+
+func (ansiDialect) QuoteIdentifier(name string) string {
+	return quoteIdentifier(name, `"`, `"`)
+}
+
+func (ansiDialect) Placeholder() (string, bool) {
+	return `?`, false
+}
+
+func (ansiDialect) Upsert(table string, keyColumns, columns []string) string {
+	q := `INSERT INTO ` + table + ` (` + strings.Join(columns, `, `) + `) VALUES (` + markers(len(columns)) + `) ON CONFLICT (` + strings.Join(keyColumns, `, `) + `) `
+
+	sets := make([]string, 0, len(columns))
+	for _, c := range nonKeyColumns(keyColumns, columns) {
+		sets = append(sets, c+` = excluded.`+c)
+	}
+
+	if len(sets) == 0 {
+		return q + `DO NOTHING`
+	}
+
+	return q + `DO UPDATE SET ` + strings.Join(sets, `, `)
+}
+
+func (ansiDialect) LastInsertIDQuery() string {
+	return ""
+}
+
+// sqlServerDialect for the sqlserver and mssql drivers
+type sqlServerDialect struct {
+	ansiDialect
+	placeholder string
+	inSequence  bool
+}
+
+func (sqlServerDialect) Name() string { return `sqlserver` }
+
+func (sqlServerDialect) RowLimiting() RowLimiting {
+	return RowLimiting{
+		Keyword:   "TOP",
+		Placement: RowLimitingFront,
+	}
+}
+
+func (sqlServerDialect) LimitOffset(limit, offset int64) string {
+	return `OFFSET ` + strconv.FormatInt(offset, 10) + ` ROWS FETCH NEXT ` + strconv.FormatInt(limit, 10) + ` ROWS ONLY`
+}
+
+func (sqlServerDialect) Savepoint(name string) string {
+	return `SAVE TRANSACTION ` + name
+}
+
+func (sqlServerDialect) RollbackToSavepoint(name string) string {
+	return `ROLLBACK TRANSACTION ` + name
+}
+
+// ReleaseSavepoint - SQL Server has no release, the savepoint lasts until the transaction ends
+func (sqlServerDialect) ReleaseSavepoint(name string) string {
+	return ""
+}
+
+func (sqlServerDialect) QuoteIdentifier(name string) string {
+	return quoteIdentifier(name, `[`, `]`)
+}
+
+func (d sqlServerDialect) Placeholder() (string, bool) {
+	return d.placeholder, d.inSequence
+}
+
+func (sqlServerDialect) Upsert(table string, keyColumns, columns []string) string {
+
+	src := make([]string, len(columns))
+	ins := make([]string, len(columns))
+	for i, c := range columns {
+		src[i] = `? AS ` + c
+		ins[i] = `source.` + c
+	}
+
+	on := make([]string, len(keyColumns))
+	for i, c := range keyColumns {
+		on[i] = `target.` + c + ` = source.` + c
+	}
+
+	q := `MERGE INTO ` + table + ` WITH (HOLDLOCK) AS target USING (SELECT ` + strings.Join(src, `, `) + `) AS source ON ` + strings.Join(on, ` AND `)
+
+	sets := make([]string, 0, len(columns))
+	for _, c := range nonKeyColumns(keyColumns, columns) {
+		sets = append(sets, `target.`+c+` = source.`+c)
+	}
+
+	if len(sets) > 0 {
+		q += ` WHEN MATCHED THEN UPDATE SET ` + strings.Join(sets, `, `)
+	}
+
+	return q + ` WHEN NOT MATCHED THEN INSERT (` + strings.Join(columns, `, `) + `) VALUES (` + strings.Join(ins, `, `) + `);`
+}
+
+func (sqlServerDialect) LastInsertIDQuery() string {
+	return `SELECT SCOPE_IDENTITY();`
+}
+
+// postgresDialect for the postgres and pgx drivers
+type postgresDialect struct {
+	ansiDialect
+}
+
+func (postgresDialect) Name() string { return `postgres` }
+
+func (postgresDialect) Placeholder() (string, bool) {
+	return `$`, true
+}
+
+func (postgresDialect) LastInsertIDQuery() string {
+	return `SELECT lastval();`
+}
+
+// sqliteDialect for the sqlite3 driver
+type sqliteDialect struct {
+	ansiDialect
+}
+
+func (sqliteDialect) Name() string { return `sqlite3` }
+
+// mysqlDialect for the mysql driver
+type mysqlDialect struct {
+	ansiDialect
+}
+
+func (mysqlDialect) Name() string { return `mysql` }
+
+func (mysqlDialect) QuoteIdentifier(name string) string {
+	return quoteIdentifier(name, "`", "`")
+}
+
+func (mysqlDialect) Upsert(table string, keyColumns, columns []string) string {
+	q := `INSERT INTO ` + table + ` (` + strings.Join(columns, `, `) + `) VALUES (` + markers(len(columns)) + `) ON DUPLICATE KEY UPDATE `
+
+	sets := make([]string, 0, len(columns))
+	for _, c := range nonKeyColumns(keyColumns, columns) {
+		sets = append(sets, c+` = VALUES(`+c+`)`)
+	}
+
+	// MySQL has no DO NOTHING, setting a key to itself keeps the row
+	if len(sets) == 0 && len(keyColumns) > 0 {
+		sets = append(sets, keyColumns[0]+` = `+keyColumns[0])
+	}
+
+	return q + strings.Join(sets, `, `)
+}
+
+// quoteIdentifier quotes each part of a dotted name. Parts already quoted are left as is.
+func quoteIdentifier(name, open, close string) string {
+	parts := strings.Split(name, `.`)
+	for i, p := range parts {
+		if p == `*` || (strings.HasPrefix(p, open) && strings.HasSuffix(p, close)) {
+			continue
+		}
+		parts[i] = open + strings.ReplaceAll(p, close, close+close) + close
+	}
+	return strings.Join(parts, `.`)
+}
+
+// markers returns n comma separated ? markers
+func markers(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.Repeat(`?, `, n-1) + `?`
+}
+
+// nonKeyColumns returns the columns that are not part of the key
+func nonKeyColumns(keyColumns, columns []string) []string {
+	res := make([]string, 0, len(columns))
+	for _, c := range columns {
+		iskey := false
+		for _, k := range keyColumns {
+			if strings.EqualFold(c, k) {
+				iskey = true
+				break
+			}
+		}
+		if !iskey {
+			res = append(res, c)
+		}
+	}
+	return res
+}
@@ -0,0 +1,125 @@
+package datahelper
+
+import (
+	"strings"
+	"testing"
+
+	cfg "github.com/eaglebush/config"
+)
+
+func TestGetDialect(t *testing.T) {
+	tests := []struct {
+		driver string
+		name   string
+		place  RowLimitPlacement
+	}{
+		{`sqlserver`, `sqlserver`, RowLimitingFront},
+		{`mssql`, `sqlserver`, RowLimitingFront},
+		{`postgres`, `postgres`, RowLimitingRear},
+		{`sqlite3`, `sqlite3`, RowLimitingRear},
+		{`MySQL`, `mysql`, RowLimitingRear},
+		{`unknown`, `ansi`, RowLimitingRear},
+	}
+
+	for _, tc := range tests {
+		d := GetDialect(tc.driver)
+		if d.Name() != tc.name || d.RowLimiting().Placement != tc.place {
+			t.Errorf("%s: got %s %v", tc.driver, d.Name(), d.RowLimiting())
+		}
+	}
+
+	// the driver name is of this test alone, and it is removed when the test ends
+	custom := `custom_` + t.Name()
+	RegisterDialect(custom, mysqlDialect{})
+	t.Cleanup(func() {
+		dialectMu.Lock()
+		defer dialectMu.Unlock()
+		delete(dialects, strings.ToLower(custom))
+	})
+
+	if GetDialect(custom).Name() != `mysql` {
+		t.Error("registered dialect was not returned")
+	}
+}
+
+func TestConnectionPlaceholder(t *testing.T) {
+	id := `DEFAULT`
+	config := &cfg.Configuration{
+		DefaultDatabaseID: &id,
+		Databases: &[]cfg.DatabaseInfo{
+			{ID: id, ConnectionString: `host=db`, DriverName: `postgres`},
+		},
+	}
+
+	// an empty placeholder takes the one of the dialect
+	c, err := newConnection(``, config)
+	if err != nil || c.info.ParameterPlaceholder != `$` || !c.info.ParameterInSequence {
+		t.Fatalf("unexpected placeholder %q, %v", c.info.ParameterPlaceholder, err)
+	}
+	if (*config.Databases)[0].ParameterPlaceholder != `` {
+		t.Fatal("expected the configuration to be left as it is")
+	}
+
+	// a configured placeholder is kept, even if it is ?
+	(*config.Databases)[0].ParameterPlaceholder = `?`
+	if c, err = newConnection(``, config); err != nil || c.info.ParameterPlaceholder != `?` {
+		t.Fatalf("unexpected placeholder %q, %v", c.info.ParameterPlaceholder, err)
+	}
+}
+
+func TestDialectUpsert(t *testing.T) {
+	keys := []string{`SequenceName`}
+	cols := []string{`SequenceName`, `SequenceNo`}
+
+	tests := []struct {
+		driver string
+		want   string
+	}{
+		{`sqlite3`, `INSERT INTO KEYGENERATOR (SequenceName, SequenceNo) VALUES (?, ?) ON CONFLICT (SequenceName) DO UPDATE SET SequenceNo = excluded.SequenceNo`},
+		{`mysql`, `INSERT INTO KEYGENERATOR (SequenceName, SequenceNo) VALUES (?, ?) ON DUPLICATE KEY UPDATE SequenceNo = VALUES(SequenceNo)`},
+		{`sqlserver`, `MERGE INTO KEYGENERATOR WITH (HOLDLOCK) AS target USING (SELECT ? AS SequenceName, ? AS SequenceNo) AS source ON target.SequenceName = source.SequenceName WHEN MATCHED THEN UPDATE SET target.SequenceNo = source.SequenceNo WHEN NOT MATCHED THEN INSERT (SequenceName, SequenceNo) VALUES (source.SequenceName, source.SequenceNo);`},
+	}
+
+	for _, tc := range tests {
+		if got := GetDialect(tc.driver).Upsert(`KEYGENERATOR`, keys, cols); got != tc.want {
+			t.Errorf("%s: got %q", tc.driver, got)
+		}
+	}
+
+	if got := GetDialect(`postgres`).Upsert(`t`, keys, keys); got != `INSERT INTO t (SequenceName) VALUES (?) ON CONFLICT (SequenceName) DO NOTHING` {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	if got := GetDialect(`sqlserver`).QuoteIdentifier(`dbo.user]s`); got != `[dbo].[user]]s]` {
+		t.Errorf("got %q", got)
+	}
+
+	if got := GetDialect(`postgres`).QuoteIdentifier(`public."config"`); got != `"public"."config"` {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestInsertOutputClause(t *testing.T) {
+	out := `OUTPUT INSERTED.UserKey `
+
+	tests := []struct {
+		query string
+		want  string
+		ok    bool
+	}{
+		{`INSERT INTO {USERACCOUNT} (UserName, [Values]) VALUES (?, 'select')`, `INSERT INTO {USERACCOUNT} (UserName, [Values]) OUTPUT INSERTED.UserKey VALUES (?, 'select')`, true},
+		{`INSERT INTO t (a) SELECT a FROM s`, `INSERT INTO t (a) OUTPUT INSERTED.UserKey SELECT a FROM s`, true},
+		{`INSERT INTO t DEFAULT VALUES`, `INSERT INTO t OUTPUT INSERTED.UserKey DEFAULT VALUES`, true},
+		{`INSERT INTO t_values (a) VALUES (1)`, `INSERT INTO t_values (a) OUTPUT INSERTED.UserKey VALUES (1)`, true},
+		{`INSERT INTO t (a)`, `INSERT INTO t (a)`, false},
+	}
+
+	for _, tc := range tests {
+		got, ok := insertOutputClause(tc.query, out, sqlSyntax{brackets: true})
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("got %q %v, want %q", got, ok, tc.want)
+		}
+	}
+}
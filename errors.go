@@ -0,0 +1,250 @@
+package datahelper
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Errors returned by DataHelper
+var (
+	ErrNotConnected          = errors.New("No active connections")
+	ErrUnknownConnectionID   = errors.New("Connection ID does not exist")
+	ErrNoConnectionString    = errors.New("Connection string is not set")
+	ErrParentConnection      = errors.New("DataHelper does not disconnect a parent connection")
+	ErrNoTransaction         = errors.New("No transaction was initiated")
+	ErrNestedTransaction     = errors.New("DataHelper does not allow a new transaction")
+	ErrParentTransaction     = errors.New("DataHelper does not allow to rollback a parent transaction")
+	ErrNotInTransaction      = errors.New("The current DataHelper instance is not in a built-in transaction")
+	ErrNoPointID             = errors.New("No point id was specified")
+	ErrInvalidPointID        = errors.New("Point id must only have letters, digits and underscores")
+	ErrUnknownPoint          = errors.New("Save point was not set in the current transaction")
+	ErrNoColumn              = errors.New("No column was specified")
+	ErrNoTableName           = errors.New("No tablename was specified")
+	ErrNamedParamMissing     = errors.New("Named parameter has no value")
+	ErrNamedParamUnused      = errors.New("Named parameter was not used in the query")
+	ErrEmptyList             = errors.New("List argument is empty")
+	ErrValueCount            = errors.New("Number of values does not match the columns")
+	ErrNoSequencePlaceholder = errors.New("Sequence name placeholder was not configured")
+	ErrNoOutputPosition      = errors.New("The OUTPUT clause could not be placed in the insert query")
+	ErrNoPageSize            = errors.New("Page size must be greater than zero")
+	ErrNoPageOrder           = errors.New("Keyset paging needs a key value for every order column")
+	ErrUnknownReadType       = errors.New("Read type is not known")
+	ErrUnknownTable          = errors.New("Table was not registered")
+	ErrNoIntrospection       = errors.New("Schema introspection is not supported for the driver")
+	ErrInvalidSequenceName   = errors.New("Sequence name must only have letters, digits and underscores")
+	ErrSequencePlaceholder   = errors.New("Sequence name placeholder must be a whole value, not part of a name or string")
+	ErrNoBlockSequence       = errors.New("Sequence block mode is not available with configured sequence queries")
+	ErrInvalidSequenceFormat = errors.New("Sequence format is not valid")
+	ErrNoNodeID              = errors.New("Snowflake node ID is not configured")
+	ErrInvalidNodeID         = errors.New("Snowflake node ID must be from 0 to 1023")
+)
+
+// QueryError - an error returned by the driver for a query
+type QueryError struct {
+	Op       string // DataHelper operation such as GetData or Exec
+	Query    string // Query sent to the driver, after placeholders were replaced
+	ArgCount int    // Number of arguments passed with the query
+	Err      error  // Error returned by the driver
+}
+
+func (e *QueryError) Error() string {
+	return e.Op + ` (` + strconv.Itoa(e.ArgCount) + ` args): ` + e.Err.Error()
+}
+
+// Unwrap - returns the driver error
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// resetErrors resets the error state and assumes all queries are OK
+func (dh *DataHelper) resetErrors() {
+	dh.AllQueryOK = true
+	dh.Errors = make([]string, 0)
+	dh.QueryErrors = make([]error, 0)
+}
+
+// addError records a driver error and returns it wrapped in a QueryError
+func (dh *DataHelper) addError(op, query string, argc int, err error) error {
+	qe := &QueryError{
+		Op:       op,
+		Query:    query,
+		ArgCount: argc,
+		Err:      err,
+	}
+
+	dh.AllQueryOK = false
+	dh.Errors = append(dh.Errors, err.Error())
+	dh.QueryErrors = append(dh.QueryErrors, qe)
+
+	return qe
+}
+
+// IsUniqueViolation - checks if the error is a unique or primary key constraint violation
+func IsUniqueViolation(err error) bool {
+
+	if n, ok := sqlServerErrorNumber(err); ok {
+		// 2627 unique constraint, 2601 unique index
+		return n == 2627 || n == 2601
+	}
+
+	if st, ok := sqlState(err); ok {
+		return st == `23505`
+	}
+
+	if _, ext, ok := sqliteErrorCode(err); ok {
+		// SQLITE_CONSTRAINT_UNIQUE and SQLITE_CONSTRAINT_PRIMARYKEY
+		return ext == 2067 || ext == 1555
+	}
+
+	return false
+}
+
+// IsForeignKeyViolation - checks if the error is a foreign key constraint violation
+func IsForeignKeyViolation(err error) bool {
+
+	if n, ok := sqlServerErrorNumber(err); ok {
+		// 547 is shared by all constraint conflicts
+		var e interface{ SQLErrorMessage() string }
+		return n == 547 && errors.As(err, &e) && strings.Contains(e.SQLErrorMessage(), `FOREIGN KEY`)
+	}
+
+	if st, ok := sqlState(err); ok {
+		return st == `23503`
+	}
+
+	if _, ext, ok := sqliteErrorCode(err); ok {
+		// SQLITE_CONSTRAINT_FOREIGNKEY
+		return ext == 787
+	}
+
+	return false
+}
+
+// IsDeadlock - checks if the transaction was chosen as a deadlock victim
+func IsDeadlock(err error) bool {
+
+	if n, ok := sqlServerErrorNumber(err); ok {
+		return n == 1205
+	}
+
+	if st, ok := sqlState(err); ok {
+		return st == `40P01`
+	}
+
+	if _, ext, ok := sqliteErrorCode(err); ok {
+		// SQLITE_LOCKED_SHAREDCACHE
+		return ext == 262
+	}
+
+	return false
+}
+
+// IsTimeout - checks if the query timed out, either by the context deadline,
+// the network or a lock or statement timeout of the database
+func IsTimeout(err error) bool {
+
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+
+	if n, ok := sqlServerErrorNumber(err); ok {
+		// 1222 lock request time out
+		return n == 1222
+	}
+
+	if st, ok := sqlState(err); ok {
+		// 57014 statement timeout, 55P03 lock not available
+		return st == `57014` || st == `55P03`
+	}
+
+	if code, _, ok := sqliteErrorCode(err); ok {
+		// SQLITE_BUSY after the busy timeout
+		return code == 5
+	}
+
+	return false
+}
+
+// IsRetryable - checks if the error is a deadlock or a serialization failure
+// that would likely succeed if the transaction is run again
+func IsRetryable(err error) bool {
+
+	if err == nil {
+		return false
+	}
+
+	if n, ok := sqlServerErrorNumber(err); ok {
+		// 1205 deadlock victim, 3960 snapshot isolation update conflict
+		return n == 1205 || n == 3960
+	}
+
+	if st, ok := sqlState(err); ok {
+		// 40001 serialization failure, 40P01 deadlock detected
+		return st == `40001` || st == `40P01`
+	}
+
+	if code, _, ok := sqliteErrorCode(err); ok {
+		// SQLITE_BUSY and SQLITE_LOCKED
+		return code == 5 || code == 6
+	}
+
+	return false
+}
+
+// sqlServerErrorNumber gets the error number of a go-mssqldb error
+func sqlServerErrorNumber(err error) (int32, bool) {
+	var e interface{ SQLErrorNumber() int32 }
+	if errors.As(err, &e) {
+		return e.SQLErrorNumber(), true
+	}
+	return 0, false
+}
+
+// sqlState gets the SQLSTATE code of a lib/pq error
+func sqlState(err error) (string, bool) {
+	var e interface{ SQLState() string }
+	if errors.As(err, &e) {
+		return e.SQLState(), true
+	}
+	return "", false
+}
+
+// sqliteErrorCode gets the primary and extended codes of a go-sqlite3 error.
+// The driver is not imported so that this package does not require cgo.
+func sqliteErrorCode(err error) (code, extended int, ok bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		v := reflect.ValueOf(err)
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				continue
+			}
+			v = v.Elem()
+		}
+
+		t := v.Type()
+		if t.Kind() != reflect.Struct || t.Name() != `Error` || t.PkgPath() != `github.com/mattn/go-sqlite3` {
+			continue
+		}
+
+		c, ec := v.FieldByName(`Code`), v.FieldByName(`ExtendedCode`)
+		if !c.IsValid() || !c.CanInt() || !ec.IsValid() || !ec.CanInt() {
+			continue
+		}
+
+		return int(c.Int()), int(ec.Int()), true
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,38 @@
+package datahelper
+
+import (
+	"fmt"
+	"testing"
+)
+
+type fakeSQLStateError string
+
+func (e fakeSQLStateError) Error() string    { return "pq: " + string(e) }
+func (e fakeSQLStateError) SQLState() string { return string(e) }
+
+type fakeSQLServerError int32
+
+func (e fakeSQLServerError) Error() string         { return fmt.Sprintf("mssql: %d", int32(e)) }
+func (e fakeSQLServerError) SQLErrorNumber() int32 { return int32(e) }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{fakeSQLStateError(`40001`), true},
+		{fakeSQLStateError(`40P01`), true},
+		{fakeSQLStateError(`23505`), false},
+		{fakeSQLServerError(1205), true},
+		{fmt.Errorf("wrapped: %w", fakeSQLServerError(1205)), true},
+		{fakeSQLServerError(2627), false},
+		{fmt.Errorf("plain"), false},
+		{nil, false},
+	}
+
+	for _, tc := range tests {
+		if got := IsRetryable(tc.err); got != tc.want {
+			t.Errorf("%v: got %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
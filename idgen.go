@@ -0,0 +1,199 @@
+package datahelper
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	cfg "github.com/eaglebush/config"
+)
+
+// IDGenerator - generates the keys of new rows. A generator attached to a TableDef fills its
+// KeyColumn in Insert when the key is not set.
+type IDGenerator interface {
+	NextID(ctx context.Context, dh *DataHelper) (interface{}, error)
+}
+
+// SnowflakeNodeFlag - configuration flag holding the node ID of SnowflakeGeneratorFromConfig
+const SnowflakeNodeFlag = `SnowflakeNodeID`
+
+// SequenceGenerator - generates int64 keys from a database sequence, the same as GetSequenceInt64
+type SequenceGenerator struct {
+	SequenceKey string
+}
+
+// NextID - gets the next value of the sequence
+func (g SequenceGenerator) NextID(ctx context.Context, dh *DataHelper) (interface{}, error) {
+	return dh.GetSequenceInt64Context(ctx, g.SequenceKey)
+}
+
+// UUIDv7Generator - generates time ordered UUIDs (RFC 9562 version 7) as strings. UUIDs
+// generated in the same millisecond by the same generator keep increasing.
+type UUIDv7Generator struct {
+	mu   sync.Mutex
+	last [16]byte
+}
+
+// NextID - gets a new UUID in its 36 character form
+func (g *UUIDv7Generator) NextID(ctx context.Context, dh *DataHelper) (interface{}, error) {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var u [16]byte
+	if _, err := rand.Read(u[6:]); err != nil {
+		return nil, err
+	}
+
+	ms := uint64(time.Now().UnixMilli())
+	last := binary.BigEndian.Uint64(g.last[:8]) >> 16
+	if ms <= last {
+		// the 12 bit counter after the timestamp goes up, moving to the next millisecond when it runs out
+		ms = last
+		ctr := binary.BigEndian.Uint16(g.last[6:8])&0x0fff + 1
+		if ctr > 0x0fff {
+			ms, ctr = ms+1, 0
+		}
+		binary.BigEndian.PutUint16(u[6:8], ctr)
+	} else {
+		// a new millisecond starts the counter in its lower half, leaving room to go up
+		u[6] &= 0x07
+	}
+
+	binary.BigEndian.PutUint64(u[:8], ms<<16|uint64(binary.BigEndian.Uint16(u[6:8])))
+	u[6] = u[6]&0x0f | 0x70 // version 7
+	u[8] = u[8]&0x3f | 0x80 // variant 10
+	g.last = u
+
+	var s [36]byte
+	hex.Encode(s[0:8], u[0:4])
+	hex.Encode(s[9:13], u[4:6])
+	hex.Encode(s[14:18], u[6:8])
+	hex.Encode(s[19:23], u[8:10])
+	hex.Encode(s[24:], u[10:])
+	s[8], s[13], s[18], s[23] = '-', '-', '-', '-'
+
+	return string(s[:]), nil
+}
+
+// crockford - base 32 alphabet of ULIDs
+const crockford = `0123456789ABCDEFGHJKMNPQRSTVWXYZ`
+
+// ULIDGenerator - generates ULIDs as 26 character strings. ULIDs generated in the same
+// millisecond by the same generator keep increasing.
+type ULIDGenerator struct {
+	mu   sync.Mutex
+	ms   uint64
+	rand [10]byte
+}
+
+// NextID - gets a new ULID
+func (g *ULIDGenerator) NextID(ctx context.Context, dh *DataHelper) (interface{}, error) {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(time.Now().UnixMilli())
+	if ms <= g.ms {
+		// the random part of the last ULID goes up by one, moving to the next millisecond when it runs out
+		ms = g.ms
+		i := len(g.rand) - 1
+		for ; i >= 0; i-- {
+			if g.rand[i]++; g.rand[i] != 0 {
+				break
+			}
+		}
+		if i < 0 {
+			ms++
+		}
+	} else if _, err := rand.Read(g.rand[:]); err != nil {
+		return nil, err
+	}
+	g.ms = ms
+
+	var u [16]byte
+	binary.BigEndian.PutUint16(u[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(u[2:6], uint32(ms))
+	copy(u[6:], g.rand[:])
+
+	// 128 bits in 26 characters of 5 bits, the first character holding the top 3 bits
+	hi := binary.BigEndian.Uint64(u[:8])
+	lo := binary.BigEndian.Uint64(u[8:])
+
+	var s [26]byte
+	for i := 25; i >= 0; i-- {
+		s[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(s[:]), nil
+}
+
+// SnowflakeGenerator - generates int64 keys from a millisecond timestamp, a node ID and a
+// per-millisecond counter, laid out as 41, 10 and 12 bits. Every process generating the keys
+// of a table must have its own node ID.
+type SnowflakeGenerator struct {
+	mu     sync.Mutex
+	nodeID int64
+	epoch  int64 // milliseconds of the epoch
+	ms     int64 // milliseconds since the epoch of the last key
+	seq    int64 // counter of the last key
+}
+
+// SnowflakeEpoch - start of the timestamps of Snowflake keys
+var SnowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// NewSnowflakeGenerator - creates a Snowflake generator with a node ID from 0 to 1023
+func NewSnowflakeGenerator(nodeID int64) (*SnowflakeGenerator, error) {
+
+	if nodeID < 0 || nodeID > 1023 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidNodeID, nodeID)
+	}
+
+	return &SnowflakeGenerator{
+		nodeID: nodeID,
+		epoch:  SnowflakeEpoch.UnixMilli(),
+	}, nil
+}
+
+// SnowflakeGeneratorFromConfig - creates a Snowflake generator with the node ID in the
+// SnowflakeNodeID flag of the configuration
+func SnowflakeGeneratorFromConfig(config *cfg.Configuration) (*SnowflakeGenerator, error) {
+
+	if config == nil {
+		return nil, ErrNoNodeID
+	}
+
+	id := config.Flag(SnowflakeNodeFlag).Int64()
+	if id == nil {
+		return nil, ErrNoNodeID
+	}
+
+	return NewSnowflakeGenerator(*id)
+}
+
+// NextID - gets a new key
+func (g *SnowflakeGenerator) NextID(ctx context.Context, dh *DataHelper) (interface{}, error) {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// a clock going back keeps the time of the last key, so that keys never repeat
+	ms := time.Now().UnixMilli() - g.epoch
+	if ms <= g.ms {
+		ms = g.ms
+		if g.seq++; g.seq > 0x0fff {
+			ms, g.seq = ms+1, 0
+		}
+	} else {
+		g.seq = 0
+	}
+	g.ms = ms
+
+	return ms<<22 | g.nodeID<<12 | g.seq, nil
+}
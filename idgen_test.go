@@ -0,0 +1,71 @@
+package datahelper
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	cfg "github.com/eaglebush/config"
+)
+
+func TestIDGenerators(t *testing.T) {
+	ctx := context.Background()
+
+	sf, err := NewSnowflakeGenerator(5)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	tests := []struct {
+		gen     IDGenerator
+		pattern *regexp.Regexp
+	}{
+		{&UUIDv7Generator{}, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)},
+		{&ULIDGenerator{}, regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)},
+		{sf, nil},
+	}
+
+	for _, tc := range tests {
+		var prev interface{}
+		for i := 0; i < 5000; i++ {
+			id, err := tc.gen.NextID(ctx, nil)
+			if err != nil {
+				t.Fatalf("%T: %v", tc.gen, err)
+			}
+
+			// IDs of a generator keep increasing, also within a millisecond
+			switch v := id.(type) {
+			case string:
+				if !tc.pattern.MatchString(v) {
+					t.Fatalf("%T: unexpected ID %s", tc.gen, v)
+				}
+				if prev != nil && v <= prev.(string) {
+					t.Fatalf("%T: %s is not after %s", tc.gen, v, prev)
+				}
+			case int64:
+				if v>>12&0x3ff != 5 {
+					t.Fatalf("%T: unexpected node in %d", tc.gen, v)
+				}
+				if prev != nil && v <= prev.(int64) {
+					t.Fatalf("%T: %d is not after %d", tc.gen, v, prev)
+				}
+			}
+			prev = id
+		}
+	}
+
+	if _, err := NewSnowflakeGenerator(1024); !errors.Is(err, ErrInvalidNodeID) {
+		t.Fatalf("expected ErrInvalidNodeID, got %v", err)
+	}
+
+	if _, err := SnowflakeGeneratorFromConfig(&cfg.Configuration{}); !errors.Is(err, ErrNoNodeID) {
+		t.Fatalf("expected ErrNoNodeID, got %v", err)
+	}
+
+	node := `12`
+	g, err := SnowflakeGeneratorFromConfig(&cfg.Configuration{Flags: &[]cfg.Flag{{Key: SnowflakeNodeFlag, Value: &node}}})
+	if err != nil || g.nodeID != 12 {
+		t.Fatalf("unexpected generator %+v, %v", g, err)
+	}
+}
@@ -0,0 +1,393 @@
+package datahelper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eaglebush/datatable"
+)
+
+// structFieldInfo - a mapped struct field
+type structFieldInfo struct {
+	Name     string // Column name of the field
+	Index    []int  // Index path of the field, including embedded structs
+	ReadOnly bool   // Field is not written by the insert and update helpers
+}
+
+var (
+	structFieldCache sync.Map // map[reflect.Type][]structFieldInfo
+	timeType         = reflect.TypeOf(time.Time{})
+	scannerType      = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+	// time layouts tried when a driver returns dates as text, as SQLite does
+	timeLayouts = []string{
+		time.RFC3339Nano,
+		`2006-01-02 15:04:05.999999999-07:00`,
+		`2006-01-02T15:04:05.999999999`,
+		`2006-01-02 15:04:05.999999999`,
+		`2006-01-02 15:04:05`,
+		`2006-01-02 15:04`,
+		`2006-01-02`,
+	}
+)
+
+// Select - get data from the database and map every row into a value of type T.
+//
+// If T is a struct, columns are matched to fields with the db tag, or with the field name
+// ignoring case if the field has no tag. Fields of embedded structs are matched as if they
+// belong to the outer struct. If T is not a struct, the first column of each row is used.
+func Select[T any](dh *DataHelper, preparedQuery string, args ...interface{}) ([]T, error) {
+	return SelectContext[T](context.Background(), dh, preparedQuery, args...)
+}
+
+// SelectContext - get data from the database with a context and map every row into a value of type T
+func SelectContext[T any](ctx context.Context, dh *DataHelper, preparedQuery string, args ...interface{}) ([]T, error) {
+
+	dt, err := dh.GetDataContext(ctx, preparedQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]T, dt.RowCount)
+	for i := range dt.Rows {
+		if err = MapRow(&dt.Rows[i], &res[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// Get - get the first row of a query mapped into a value of type T.
+// It returns sql.ErrNoRows if the query has no result.
+func Get[T any](dh *DataHelper, preparedQuery string, args ...interface{}) (T, error) {
+	return GetContext[T](context.Background(), dh, preparedQuery, args...)
+}
+
+// GetContext - get the first row of a query with a context mapped into a value of type T
+func GetContext[T any](ctx context.Context, dh *DataHelper, preparedQuery string, args ...interface{}) (T, error) {
+
+	var res T
+
+	dt, err := dh.GetDataContext(ctx, preparedQuery, args...)
+	if err != nil {
+		return res, err
+	}
+
+	if dt.RowCount == 0 {
+		return res, sql.ErrNoRows
+	}
+
+	err = MapRow(&dt.Rows[0], &res)
+	return res, err
+}
+
+// MapRow - maps the cells of a datatable row into the value pointed to by dest.
+// Cells without a matching field are ignored.
+func MapRow(r *datatable.Row, dest interface{}) error {
+
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("Destination must be a non-nil pointer")
+	}
+
+	rv = rv.Elem()
+
+	// allocate pointer to struct destinations
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+
+	if !isStructType(rv.Type()) {
+		if len(r.Cells) == 0 {
+			return nil
+		}
+		return setFieldValue(rv, r.Cells[0].Value)
+	}
+
+	flds := structFields(rv.Type())
+	for i := range r.Cells {
+		c := &r.Cells[i]
+		for _, f := range flds {
+			if !strings.EqualFold(f.Name, c.ColumnName) {
+				continue
+			}
+
+			if err := setFieldValue(fieldByIndexAlloc(rv, f.Index), c.Value); err != nil {
+				return fmt.Errorf("Column %s: %w", c.ColumnName, err)
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+// isStructType checks if the type is a struct that would be mapped field by field
+func isStructType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && !reflect.PointerTo(t).Implements(scannerType)
+}
+
+// structFields returns the mapped fields of a struct type. The result is cached per type.
+func structFields(t reflect.Type) []structFieldInfo {
+
+	if v, ok := structFieldCache.Load(t); ok {
+		return v.([]structFieldInfo)
+	}
+
+	flds := make([]structFieldInfo, 0, t.NumField())
+	collectStructFields(t, nil, &flds)
+
+	structFieldCache.Store(t, flds)
+	return flds
+}
+
+func collectStructFields(t reflect.Type, parent []int, flds *[]structFieldInfo) {
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		name, opts, tagged := strings.Cut(sf.Tag.Get(`db`), `,`)
+		if name == `-` {
+			continue
+		}
+		tagged = tagged || name != ""
+
+		idx := make([]int, len(parent)+1)
+		copy(idx, parent)
+		idx[len(parent)] = i
+
+		// embedded structs without a tag have their fields promoted. Like encoding/json, pointers
+		// to unexported structs are skipped, since they could not be allocated.
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			if sf.Anonymous && !sf.IsExported() {
+				continue
+			}
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && isStructType(ft) {
+			collectStructFields(ft, idx, flds)
+			continue
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		fi := structFieldInfo{
+			Name:  name,
+			Index: idx,
+		}
+
+		if tagged {
+			for _, o := range strings.Split(opts, `,`) {
+				if strings.TrimSpace(o) == `readonly` {
+					fi.ReadOnly = true
+				}
+			}
+		}
+
+		*flds = append(*flds, fi)
+	}
+}
+
+// fieldByIndexAlloc returns the nested field by index, allocating nil embedded pointers along the way
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// setFieldValue converts a value returned by the driver into the field type
+func setFieldValue(fv reflect.Value, value interface{}) error {
+
+	if value == nil {
+		fv.Set(reflect.Zero(fv.Type()))
+		return nil
+	}
+
+	if fv.Kind() == reflect.Pointer {
+		nv := reflect.New(fv.Type().Elem())
+		if err := setFieldValue(nv.Elem(), value); err != nil {
+			return err
+		}
+		fv.Set(nv)
+		return nil
+	}
+
+	if fv.CanAddr() && fv.Addr().Type().Implements(scannerType) {
+		return fv.Addr().Interface().(sql.Scanner).Scan(value)
+	}
+
+	vv := reflect.ValueOf(value)
+	if vv.Type().AssignableTo(fv.Type()) {
+		fv.Set(vv)
+		return nil
+	}
+
+	// text representation of the value, for drivers returning []byte
+	var (
+		txt    string
+		istext bool
+	)
+	switch v := value.(type) {
+	case string:
+		txt, istext = v, true
+	case []byte:
+		txt, istext = string(v), true
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		if istext {
+			fv.SetString(txt)
+			return nil
+		}
+		if t, ok := value.(time.Time); ok {
+			fv.SetString(t.Format(time.RFC3339Nano))
+			return nil
+		}
+		fv.SetString(fmt.Sprint(value))
+		return nil
+
+	case reflect.Bool:
+		switch {
+		case istext:
+			b, err := strconv.ParseBool(strings.TrimSpace(txt))
+			if err != nil {
+				return err
+			}
+			fv.SetBool(b)
+			return nil
+		case vv.CanInt():
+			fv.SetBool(vv.Int() != 0)
+			return nil
+		case vv.CanUint():
+			fv.SetBool(vv.Uint() != 0)
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch {
+		case istext:
+			n, err := strconv.ParseInt(strings.TrimSpace(txt), 10, 64)
+			if err != nil {
+				return err
+			}
+			fv.SetInt(n)
+			return nil
+		case vv.CanInt():
+			fv.SetInt(vv.Int())
+			return nil
+		case vv.CanUint():
+			fv.SetInt(int64(vv.Uint()))
+			return nil
+		case vv.CanFloat():
+			fv.SetInt(int64(vv.Float()))
+			return nil
+		case vv.Kind() == reflect.Bool:
+			if vv.Bool() {
+				fv.SetInt(1)
+			} else {
+				fv.SetInt(0)
+			}
+			return nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch {
+		case istext:
+			n, err := strconv.ParseUint(strings.TrimSpace(txt), 10, 64)
+			if err != nil {
+				return err
+			}
+			fv.SetUint(n)
+			return nil
+		case vv.CanInt():
+			fv.SetUint(uint64(vv.Int()))
+			return nil
+		case vv.CanUint():
+			fv.SetUint(vv.Uint())
+			return nil
+		case vv.CanFloat():
+			fv.SetUint(uint64(vv.Float()))
+			return nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		switch {
+		case istext:
+			n, err := strconv.ParseFloat(strings.TrimSpace(txt), 64)
+			if err != nil {
+				return err
+			}
+			fv.SetFloat(n)
+			return nil
+		case vv.CanInt():
+			fv.SetFloat(float64(vv.Int()))
+			return nil
+		case vv.CanUint():
+			fv.SetFloat(float64(vv.Uint()))
+			return nil
+		case vv.CanFloat():
+			fv.SetFloat(vv.Float())
+			return nil
+		}
+
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.Uint8 && istext {
+			fv.SetBytes([]byte(txt))
+			return nil
+		}
+
+	case reflect.Struct:
+		if fv.Type() == timeType && istext {
+			t, err := parseTime(txt)
+			if err != nil {
+				return err
+			}
+			fv.Set(reflect.ValueOf(t))
+			return nil
+		}
+	}
+
+	if vv.Type().ConvertibleTo(fv.Type()) {
+		fv.Set(vv.Convert(fv.Type()))
+		return nil
+	}
+
+	return fmt.Errorf("Cannot convert %T to %s", value, fv.Type())
+}
+
+// parseTime parses dates stored as text
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, l := range timeLayouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Cannot parse %q as time", s)
+}
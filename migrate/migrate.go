@@ -0,0 +1,488 @@
+// Package migrate applies versioned schema migrations through a DataHelper.
+//
+// Migrations are pairs of SQL files named <version>_<name>.up.sql and <version>_<name>.down.sql,
+// such as 0001_create_useraccount.up.sql. The down file is optional but a migration without it
+// could not be rolled back. A file is sent as one batch, unless it has lines with only GO on
+// them, which split it into batches like in SQL Server tools. Queries could use {table} placeholders.
+//
+// Applied versions are recorded with a checksum of their files in a tracking table. A recorded
+// migration whose files were edited or removed stops Up, Down and To until it is resolved.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dh "github.com/eaglebush/datahelper"
+)
+
+// Errors returned by the migrator
+var (
+	ErrInvalidFileName  = errors.New("Migration file name must be <version>_<name>.up.sql or <version>_<name>.down.sql")
+	ErrDuplicateVersion = errors.New("Migration version is used more than once")
+	ErrNoUp             = errors.New("Migration has no up file")
+	ErrNoDown           = errors.New("Migration has no down file")
+	ErrEdited           = errors.New("Applied migration was edited")
+	ErrMissing          = errors.New("Applied migration file is missing")
+	ErrUnknownVersion   = errors.New("Migration version does not exist")
+)
+
+// DefaultTable - name of the tracking table if the Migrator has none set
+const DefaultTable = `schema_migrations`
+
+// State - state of a migration
+type State string
+
+// States of a migration
+const (
+	StatePending State = `pending` // Not applied yet
+	StateApplied State = `applied` // Applied and unchanged
+	StateEdited  State = `edited`  // Applied, but its files changed since
+	StateMissing State = `missing` // Applied, but its files no longer exist
+)
+
+// Migration - a versioned schema change
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string // SQL applying the change
+	Down     string // SQL reverting the change, empty if it could not be reverted
+	Checksum string // SHA-256 of the up and down SQL
+}
+
+// Status - a migration and its state
+type Status struct {
+	Version   int64
+	Name      string
+	State     State
+	AppliedAt time.Time // Zero if pending
+}
+
+// appliedRow - a row of the tracking table
+type appliedRow struct {
+	Version   int64     `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// Migrator - applies migrations to the database of a DataHelper
+type Migrator struct {
+	Table      string // Tracking table. DefaultTable if empty. Unqualified names are in the schema of the database.
+	db         *dh.DataHelper
+	migrations []Migration // sorted by version
+}
+
+// New - creates a migrator with the migrations in the root of a file system, such as an
+// embed.FS narrowed with fs.Sub. Files that do not end in .sql are ignored.
+func New(db *dh.DataHelper, fsys fs.FS) (*Migrator, error) {
+
+	ms, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: ms,
+	}, nil
+}
+
+// NewFromDir - creates a migrator with the migrations in a directory
+func NewFromDir(db *dh.DataHelper, dir string) (*Migrator, error) {
+	return New(db, os.DirFS(dir))
+}
+
+// Load - reads the migrations in the root of a file system, sorted by version
+func Load(fsys fs.FS) ([]Migration, error) {
+
+	entries, err := fs.ReadDir(fsys, `.`)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		fn := e.Name()
+		if e.IsDir() || path.Ext(fn) != `.sql` {
+			continue
+		}
+
+		base := strings.TrimSuffix(fn, `.sql`)
+		up := strings.HasSuffix(base, `.up`)
+		if !up && !strings.HasSuffix(base, `.down`) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFileName, fn)
+		}
+		base = strings.TrimSuffix(strings.TrimSuffix(base, `.up`), `.down`)
+
+		vs, name, _ := strings.Cut(base, `_`)
+		ver, err := strconv.ParseInt(vs, 10, 64)
+		if err != nil || name == "" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFileName, fn)
+		}
+
+		b, err := fs.ReadFile(fsys, fn)
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[ver]
+		if m == nil {
+			m = &Migration{Version: ver, Name: name}
+			byVersion[ver] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("%w: %d is %s and %s", ErrDuplicateVersion, ver, m.Name, name)
+		}
+
+		if (up && m.Up != "") || (!up && m.Down != "") {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateVersion, fn)
+		}
+
+		if up {
+			m.Up = string(b)
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	ms := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("%w: %d_%s", ErrNoUp, m.Version, m.Name)
+		}
+
+		sum := sha256.Sum256([]byte(m.Up + "\x00" + m.Down))
+		m.Checksum = hex.EncodeToString(sum[:])
+		ms = append(ms, *m)
+	}
+
+	sort.Slice(ms, func(i, j int) bool {
+		return ms[i].Version < ms[j].Version
+	})
+
+	return ms, nil
+}
+
+// Migrations - gets the migrations of the migrator, sorted by version
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Status - gets the state of every migration, including applied migrations whose files are missing
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]Status, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		st := Status{
+			Version: mg.Version,
+			Name:    mg.Name,
+			State:   StatePending,
+		}
+
+		if a, ok := applied[mg.Version]; ok {
+			st.AppliedAt = a.AppliedAt
+			st.State = StateApplied
+			if a.Checksum != mg.Checksum {
+				st.State = StateEdited
+			}
+			delete(applied, mg.Version)
+		}
+
+		res = append(res, st)
+	}
+
+	for _, a := range applied {
+		res = append(res, Status{
+			Version:   a.Version,
+			Name:      a.Name,
+			State:     StateMissing,
+			AppliedAt: a.AppliedAt,
+		})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Version < res[j].Version
+	})
+
+	return res, nil
+}
+
+// Up - applies all pending migrations in version order and returns how many were applied
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	return m.To(ctx, -1)
+}
+
+// Down - reverts the last n applied migrations in reverse order and returns how many were reverted
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+
+	states, err := m.check(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	done := 0
+	for i := len(states) - 1; i >= 0 && done < n; i-- {
+		if states[i].State != StateApplied {
+			continue
+		}
+		if err = m.revert(ctx, m.migration(states[i].Version)); err != nil {
+			return done, err
+		}
+		done++
+	}
+
+	return done, nil
+}
+
+// To - migrates up or down to a version and returns how many migrations were applied or reverted.
+// Migrations up to the version are applied and the ones after it are reverted. Version 0 reverts
+// all migrations, and a negative version applies all of them.
+func (m *Migrator) To(ctx context.Context, version int64) (int, error) {
+
+	if version > 0 && m.migration(version) == nil {
+		return 0, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	states, err := m.check(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	done := 0
+
+	for i := len(states) - 1; i >= 0 && version >= 0; i-- {
+		if states[i].State != StateApplied || states[i].Version <= version {
+			continue
+		}
+		if err = m.revert(ctx, m.migration(states[i].Version)); err != nil {
+			return done, err
+		}
+		done++
+	}
+
+	for _, st := range states {
+		if st.State != StatePending || (version >= 0 && st.Version > version) {
+			continue
+		}
+		if err = m.apply(ctx, m.migration(st.Version)); err != nil {
+			return done, err
+		}
+		done++
+	}
+
+	return done, nil
+}
+
+// check gets the states of the migrations and fails on edited or missing migrations
+func (m *Migrator) check(ctx context.Context) ([]Status, error) {
+
+	states, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, st := range states {
+		switch st.State {
+		case StateEdited:
+			errs = append(errs, fmt.Errorf("%w: %d_%s", ErrEdited, st.Version, st.Name))
+		case StateMissing:
+			errs = append(errs, fmt.Errorf("%w: %d_%s", ErrMissing, st.Version, st.Name))
+		}
+	}
+
+	return states, errors.Join(errs...)
+}
+
+// apply runs the up SQL of a migration and records it
+func (m *Migrator) apply(ctx context.Context, mg *Migration) error {
+
+	return m.run(ctx, mg.Up, func(db *dh.DataHelper) error {
+		_, err := db.InsertInto(m.table()).
+			Columns(`version`, `name`, `checksum`, `applied_at`).
+			Values(mg.Version, mg.Name, mg.Checksum, time.Now().UTC()).
+			ExecContext(ctx)
+		return err
+	}, mg)
+}
+
+// revert runs the down SQL of a migration and removes its record
+func (m *Migrator) revert(ctx context.Context, mg *Migration) error {
+
+	if strings.TrimSpace(mg.Down) == "" {
+		return fmt.Errorf("%w: %d_%s", ErrNoDown, mg.Version, mg.Name)
+	}
+
+	return m.run(ctx, mg.Down, func(db *dh.DataHelper) error {
+		_, err := db.DeleteContext(ctx, m.table(), `version = ?`, mg.Version)
+		return err
+	}, mg)
+}
+
+// run executes the batches of a migration and updates the tracking table, in a transaction
+// if the database could roll back DDL
+func (m *Migrator) run(ctx context.Context, sql string, track func(*dh.DataHelper) error, mg *Migration) error {
+
+	fn := func(db *dh.DataHelper) error {
+		for _, b := range batches(sql) {
+			if _, err := db.ExecContext(ctx, b); err != nil {
+				return fmt.Errorf("Migration %d_%s: %w", mg.Version, mg.Name, err)
+			}
+		}
+		return track(db)
+	}
+
+	if !m.transactionalDDL() {
+		return fn(m.db)
+	}
+
+	return m.db.InTransaction(ctx, nil, fn)
+}
+
+// applied gets the recorded migrations, creating the tracking table if it does not exist
+func (m *Migrator) applied(ctx context.Context) (map[int64]appliedRow, error) {
+
+	// the tracking table is created on first use
+	exists, err := m.tableExists(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return map[int64]appliedRow{}, m.createTable(ctx)
+	}
+
+	q, _ := m.db.Select(`version`, `name`, `checksum`, `applied_at`).From(m.table()).Query()
+	rows, err := dh.SelectContext[appliedRow](ctx, m.db, q)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[int64]appliedRow, len(rows))
+	for _, r := range rows {
+		res[r.Version] = r
+	}
+
+	return res, nil
+}
+
+// tableExists checks the catalog of the database for the tracking table
+func (m *Migrator) tableExists(ctx context.Context) (bool, error) {
+
+	cols, err := m.db.ColumnsContext(ctx, m.table())
+	if !errors.Is(err, dh.ErrNoIntrospection) {
+		return len(cols) > 0, err
+	}
+
+	name := strings.Trim(m.table(), `{}`)
+	if i := strings.LastIndexByte(name, '.'); i != -1 {
+		name = name[i+1:]
+	}
+
+	q := `SELECT COUNT(*) FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_NAME = ?`
+	if m.dialect() == `mysql` {
+		q += ` AND TABLE_SCHEMA = DATABASE()`
+	}
+
+	n, err := dh.GetContext[int64](ctx, m.db, q+`;`, name)
+	return n > 0, err
+}
+
+func (m *Migrator) createTable(ctx context.Context) error {
+
+	ts := `TIMESTAMP`
+	if m.dialect() == `sqlserver` {
+		ts = `DATETIME2`
+	}
+
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE `+m.table()+` (
+		version BIGINT NOT NULL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum VARCHAR(64) NOT NULL,
+		applied_at `+ts+` NOT NULL
+	)`)
+
+	return err
+}
+
+// transactionalDDL checks if schema changes could be rolled back with the transaction they are in.
+// MySQL commits implicitly on DDL.
+func (m *Migrator) transactionalDDL() bool {
+	switch m.dialect() {
+	case `postgres`, `sqlserver`, `sqlite3`:
+		return true
+	}
+	return false
+}
+
+func (m *Migrator) dialect() string {
+	if m.db.Dialect != nil {
+		return m.db.Dialect.Name()
+	}
+	return dh.GetDialect(m.db.DriverName).Name()
+}
+
+// table gets the name of the tracking table. An unqualified name is marked as {table}, so that
+// it is in the schema of the database information both when it is created and when it is read.
+func (m *Migrator) table() string {
+	t := m.Table
+	if t == "" {
+		t = DefaultTable
+	}
+	if !strings.ContainsAny(t, `.{`) {
+		t = `{` + t + `}`
+	}
+	return t
+}
+
+func (m *Migrator) migration(version int64) *Migration {
+	i := sort.Search(len(m.migrations), func(i int) bool {
+		return m.migrations[i].Version >= version
+	})
+	if i < len(m.migrations) && m.migrations[i].Version == version {
+		return &m.migrations[i]
+	}
+	return nil
+}
+
+// batches splits SQL into the batches between lines with only GO on them
+func batches(sql string) []string {
+
+	var (
+		res []string
+		cur strings.Builder
+	)
+
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			res = append(res, s)
+		}
+		cur.Reset()
+	}
+
+	for _, ln := range strings.SplitAfter(sql, "\n") {
+		if strings.EqualFold(strings.TrimSpace(ln), `GO`) {
+			flush()
+			continue
+		}
+		cur.WriteString(ln)
+	}
+	flush()
+
+	return res
+}
@@ -0,0 +1,182 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	cfg "github.com/eaglebush/config"
+	dh "github.com/eaglebush/datahelper"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newSQLiteHelper(t *testing.T) *dh.DataHelper {
+	t.Helper()
+
+	id := `DEFAULT`
+	db := dh.NewDataHelper(&cfg.Configuration{
+		DefaultDatabaseID: &id,
+		Databases: &[]cfg.DatabaseInfo{
+			{
+				ID:                   id,
+				ConnectionString:     filepath.Join(t.TempDir(), `test.db`),
+				DriverName:           `sqlite3`,
+				StorageType:          `FILE`,
+				ParameterPlaceholder: `?`,
+			},
+		},
+	})
+
+	if _, err := db.Connect(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	t.Cleanup(func() { db.Disconnect(false) })
+
+	return db
+}
+
+func TestMigrator(t *testing.T) {
+	db := newSQLiteHelper(t)
+	ctx := context.Background()
+
+	fsys := fstest.MapFS{
+		`0001_create_useraccount.up.sql`:   {Data: []byte(`CREATE TABLE {USERACCOUNT} (UserKey INTEGER PRIMARY KEY, UserName TEXT NOT NULL);`)},
+		`0001_create_useraccount.down.sql`: {Data: []byte(`DROP TABLE {USERACCOUNT};`)},
+		`0002_create_role.up.sql`:          {Data: []byte("CREATE TABLE ROLE (RoleCode TEXT PRIMARY KEY);\nGO\nINSERT INTO ROLE (RoleCode) VALUES ('admin');")},
+		`0002_create_role.down.sql`:        {Data: []byte(`DROP TABLE ROLE;`)},
+		`0003_bad.up.sql`:                  {Data: []byte(`CREATE TABLE NOTES (NoteKey INTEGER PRIMARY KEY); INSERT INTO NOSUCHTABLE VALUES (1);`)},
+		`README.md`:                        {Data: []byte(`ignored`)},
+	}
+
+	m, err := New(db, fsys)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if n, err := m.To(ctx, 2); err != nil || n != 2 {
+		t.Fatalf("unexpected result %d, %v", n, err)
+	}
+
+	// a failed migration is rolled back with its DDL
+	if n, err := m.Up(ctx); err == nil || n != 0 {
+		t.Fatalf("expected the bad migration to fail, got %d, %v", n, err)
+	}
+	if tables, _ := db.Tables(); len(tables) != 3 {
+		t.Fatalf("expected the failed migration to be rolled back, got %v", tables)
+	}
+
+	st, err := m.Status(ctx)
+	if err != nil || len(st) != 3 || st[0].State != StateApplied || st[1].State != StateApplied || st[2].State != StatePending {
+		t.Fatalf("unexpected status %+v, %v", st, err)
+	}
+
+	if n, err := m.Down(ctx, 1); err != nil || n != 1 {
+		t.Fatalf("unexpected result %d, %v", n, err)
+	}
+	if tables, _ := db.Tables(); len(tables) != 2 {
+		t.Fatalf("expected ROLE to be dropped, got %v", tables)
+	}
+
+	// edited and missing migrations stop the migrator
+	fsys[`0001_create_useraccount.down.sql`] = &fstest.MapFile{Data: []byte(`DROP TABLE USERACCOUNT;`)}
+	delete(fsys, `0003_bad.up.sql`)
+	if m, err = New(db, fsys); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err = m.Up(ctx); !errors.Is(err, ErrEdited) {
+		t.Fatalf("expected ErrEdited, got %v", err)
+	}
+
+	delete(fsys, `0001_create_useraccount.up.sql`)
+	delete(fsys, `0001_create_useraccount.down.sql`)
+	if m, err = New(db, fsys); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err = m.Down(ctx, 1); !errors.Is(err, ErrMissing) {
+		t.Fatalf("expected ErrMissing, got %v", err)
+	}
+}
+
+func TestMigratorCancelled(t *testing.T) {
+	db := newSQLiteHelper(t)
+
+	m, err := New(db, fstest.MapFS{`0001_create_role.up.sql`: {Data: []byte(`CREATE TABLE ROLE (RoleCode TEXT PRIMARY KEY);`)}})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// the error of the context is returned as it is, without trying to create the tracking table
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = m.Up(ctx)
+	if _, joined := err.(interface{ Unwrap() []error }); joined || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected only context.Canceled, got %v", err)
+	}
+	if tables, _ := db.Tables(); len(tables) != 0 {
+		t.Fatalf("expected no tables, got %v", tables)
+	}
+}
+
+func TestMigratorSchema(t *testing.T) {
+	ctx := context.Background()
+
+	// the only connection of the pool has the schema attached
+	id, one := `DEFAULT`, 1
+	db := dh.NewDataHelper(&cfg.Configuration{
+		DefaultDatabaseID: &id,
+		Databases: &[]cfg.DatabaseInfo{
+			{
+				ID:                   id,
+				ConnectionString:     filepath.Join(t.TempDir(), `test.db`),
+				DriverName:           `sqlite3`,
+				StorageType:          `FILE`,
+				ParameterPlaceholder: `?`,
+				Schema:               `aux`,
+				MaxOpenConnection:    &one,
+			},
+		},
+	})
+	if _, err := db.Connect(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	t.Cleanup(func() { db.Disconnect(false) })
+
+	if _, err := db.Exec(`ATTACH DATABASE ? AS aux;`, filepath.Join(t.TempDir(), `aux.db`)); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	fsys := fstest.MapFS{
+		`0001_create_role.up.sql`:   {Data: []byte(`CREATE TABLE {ROLE} (RoleCode TEXT PRIMARY KEY);`)},
+		`0001_create_role.down.sql`: {Data: []byte(`DROP TABLE {ROLE};`)},
+	}
+
+	// the tracking table is created in the schema it is looked for
+	for i := 0; i < 2; i++ {
+		m, err := New(db, fsys)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if _, err = m.Up(ctx); err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+	}
+
+	if n, err := dh.Get[int64](db, `SELECT COUNT(*) FROM aux.`+DefaultTable+`;`); err != nil || n != 1 {
+		t.Fatalf("expected the migration in aux, got %d, %v", n, err)
+	}
+}
+
+func TestLoadInvalidNames(t *testing.T) {
+	for _, fn := range []string{`create.up.sql`, `0001.up.sql`, `0001_x.sql`} {
+		if _, err := Load(fstest.MapFS{fn: {Data: []byte(`SELECT 1;`)}}); !errors.Is(err, ErrInvalidFileName) {
+			t.Errorf("%s: expected ErrInvalidFileName, got %v", fn, err)
+		}
+	}
+
+	if _, err := Load(fstest.MapFS{`0001_x.down.sql`: {Data: []byte(`SELECT 1;`)}}); !errors.Is(err, ErrNoUp) {
+		t.Errorf("expected ErrNoUp, got %v", err)
+	}
+}
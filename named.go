@@ -0,0 +1,166 @@
+package datahelper
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/eaglebush/datatable"
+)
+
+// BindNamed - rewrites a query with named parameters such as :userKey into the parameter
+// placeholders of the current connection and returns the arguments in the order they appear.
+//
+// The params could be a map[string]interface{} or a struct. Struct fields are matched with
+// the db tag or the field name ignoring case. A name without a value is an error, as is a map
+// entry that was never used in the query. A name appearing more than once is bound every time.
+func (dh *DataHelper) BindNamed(namedQuery string, params interface{}) (string, []interface{}, error) {
+
+	query, args, err := dh.bindNamed(namedQuery, params)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return dh.replaceQueryParamMarker(query), args, nil
+}
+
+// bindNamed rewrites the named parameters of a query into ? markers, leaving the rewrite into the
+// placeholders of the connection to the query functions so that it is only done once
+func (dh *DataHelper) bindNamed(namedQuery string, params interface{}) (string, []interface{}, error) {
+
+	lookup, unused, err := namedParamLookup(params)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		sb      strings.Builder
+		args    []interface{}
+		missing []string
+	)
+
+	for _, t := range tokenizeSQL(namedQuery, dh.syntax()) {
+		if t.Kind != tokNamedParam {
+			if t.Kind == tokPlaceholder {
+				t.Text = `{` + t.Text + `}`
+			}
+			sb.WriteString(t.Text)
+			continue
+		}
+
+		v, ok := lookup(t.Text)
+		if !ok {
+			missing = append(missing, t.Text)
+			continue
+		}
+
+		delete(unused, t.Text)
+		args = append(args, v)
+		sb.WriteString(`?`)
+	}
+
+	if len(missing) > 0 {
+		return "", nil, fmt.Errorf("%w: :%s", ErrNamedParamMissing, strings.Join(missing, `, :`))
+	}
+
+	if len(unused) > 0 {
+		names := make([]string, 0, len(unused))
+		for k := range unused {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		return "", nil, fmt.Errorf("%w: %s", ErrNamedParamUnused, strings.Join(names, `, `))
+	}
+
+	return sb.String(), args, nil
+}
+
+// GetDataNamed - get data from the database using a query with named parameters
+func (dh *DataHelper) GetDataNamed(namedQuery string, params interface{}) (*datatable.DataTable, error) {
+	return dh.GetDataNamedContext(context.Background(), namedQuery, params)
+}
+
+// GetDataNamedContext - get data from the database with a context using a query with named parameters
+func (dh *DataHelper) GetDataNamedContext(ctx context.Context, namedQuery string, params interface{}) (*datatable.DataTable, error) {
+
+	query, args, err := dh.bindNamed(namedQuery, params)
+	if err != nil {
+		return datatable.NewDataTable("data"), err
+	}
+
+	return dh.GetDataContext(ctx, query, args...)
+}
+
+// ExecNamed - execute a query with named parameters that does not return rows
+func (dh *DataHelper) ExecNamed(namedQuery string, params interface{}) (sql.Result, error) {
+	return dh.ExecNamedContext(context.Background(), namedQuery, params)
+}
+
+// ExecNamedContext - execute a query with named parameters that does not return rows with a context
+func (dh *DataHelper) ExecNamedContext(ctx context.Context, namedQuery string, params interface{}) (sql.Result, error) {
+
+	query, args, err := dh.bindNamed(namedQuery, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return dh.ExecContext(ctx, query, args...)
+}
+
+// namedParamLookup returns a lookup function for the named parameters and the names that should all be used
+func namedParamLookup(params interface{}) (func(string) (interface{}, bool), map[string]struct{}, error) {
+
+	unused := make(map[string]struct{})
+
+	if params == nil {
+		return func(string) (interface{}, bool) { return nil, false }, unused, nil
+	}
+
+	if m, ok := params.(map[string]interface{}); ok {
+		for k := range m {
+			unused[k] = struct{}{}
+		}
+		return func(n string) (interface{}, bool) {
+			v, ok := m[n]
+			return v, ok
+		}, unused, nil
+	}
+
+	rv := reflect.ValueOf(params)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil, fmt.Errorf("Named parameters must not be a nil %T", params)
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("Named parameters must be a map[string]interface{} or a struct, not %T", params)
+	}
+
+	flds := structFields(rv.Type())
+	return func(n string) (interface{}, bool) {
+		for _, f := range flds {
+			if !strings.EqualFold(f.Name, n) {
+				continue
+			}
+			fv, err := rv.FieldByIndexErr(f.Index)
+			if err != nil {
+				return nil, true
+			}
+			return fv.Interface(), true
+		}
+		return nil, false
+	}, unused, nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
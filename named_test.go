@@ -0,0 +1,68 @@
+package datahelper
+
+import (
+	"testing"
+
+	cfg "github.com/eaglebush/config"
+)
+
+func TestBindNamed(t *testing.T) {
+	dh := &DataHelper{
+		CurrentDatabaseInfo: &cfg.DatabaseInfo{
+			ParameterPlaceholder: `@p`,
+			ParameterInSequence:  true,
+		},
+	}
+
+	q, args, err := dh.BindNamed(`SELECT ':skip', x::int FROM useraccount WHERE user_key = :userKey AND active = :active OR parent_key = :userKey`,
+		map[string]interface{}{`userKey`: 1, `active`: true})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if q != `SELECT ':skip', x::int FROM useraccount WHERE user_key = @p1 AND active = @p2 OR parent_key = @p3` {
+		t.Fatalf("unexpected query %s", q)
+	}
+
+	if len(args) != 3 || args[0] != 1 || args[1] != true || args[2] != 1 {
+		t.Fatalf("unexpected args %v", args)
+	}
+
+	type filter struct {
+		Key    int `db:"userKey"`
+		Active bool
+	}
+
+	if _, args, err = dh.BindNamed(`WHERE user_key = :userKey AND active = :ACTIVE`, filter{Key: 5, Active: true}); err != nil || args[0] != 5 {
+		t.Fatalf("unexpected args %v, %v", args, err)
+	}
+
+	if _, _, err = dh.BindNamed(`WHERE user_key = :userKey AND active = :active`, map[string]interface{}{`userKey`: 1}); err == nil {
+		t.Fatal("expected an error for a missing name")
+	}
+
+	if _, _, err = dh.BindNamed(`WHERE user_key = :userKey`, map[string]interface{}{`userKey`: 1, `other`: 2}); err == nil {
+		t.Fatal("expected an error for an unused name")
+	}
+}
+
+func TestNamedQueryMarkers(t *testing.T) {
+	dh, rec := newRecorderHelper(t, `postgres`, cfg.DatabaseInfo{ParameterPlaceholder: `$`, ParameterInSequence: true})
+
+	params := map[string]interface{}{`a`: 1, `b`: 2}
+	if _, err := dh.GetDataNamed(`SELECT x FROM t WHERE data ?? 'k' AND a = :a AND b = :b`, params); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// the ?? escape is a ? operator and the markers are numbered once
+	if q := rec.last(); q != `SELECT x FROM t WHERE data ? 'k' AND a = $1 AND b = $2` {
+		t.Fatalf("unexpected query %s", q)
+	}
+
+	if _, err := dh.ExecNamed(`UPDATE t SET data = data - 'k' WHERE data ?? 'k' AND a = :a AND b = :b`, params); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if q := rec.last(); q != `UPDATE t SET data = data - 'k' WHERE data ? 'k' AND a = $1 AND b = $2` {
+		t.Fatalf("unexpected query %s", q)
+	}
+}
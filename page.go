@@ -0,0 +1,154 @@
+package datahelper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/eaglebush/datatable"
+)
+
+// PageRequest - a page of rows to get
+type PageRequest struct {
+	Page       int           // 1-based page number in offset mode
+	Size       int           // Number of rows in a page
+	OrderBy    []string      // Columns of the query to order by, such as "UserName" or "UserKey DESC". Required in keyset mode.
+	Keyset     bool          // Seek the rows after a key instead of skipping the rows of the pages before
+	After      []interface{} // Values of the OrderBy columns of the last row of the previous page in keyset mode. Empty for the first page.
+	CountTotal bool          // Count the total rows in keyset mode. Offset mode always counts.
+}
+
+// Page - a page of rows
+type Page struct {
+	Data    *datatable.DataTable // Rows of the page
+	Total   int64                // Total rows of the query. -1 if not counted.
+	HasNext bool                 // There are rows after this page
+	LastKey []interface{}        // Values of the OrderBy columns of the last row, to pass as After for the next page
+}
+
+// GetPage - gets a page of rows of a query. The query must not be ordered since the page is
+// ordered by the OrderBy columns, which must be columns returned by the query.
+//
+// In offset mode, the page is found by skipping the rows of the pages before it, using
+// OFFSET ... FETCH NEXT on SQL Server and LIMIT ... OFFSET elsewhere. In keyset mode, rows
+// are sought after the last key of the previous page, which stays fast on large tables.
+// The OrderBy columns should then be unique together, like ending with the primary key.
+func (dh *DataHelper) GetPage(query string, req PageRequest, args ...interface{}) (*Page, error) {
+	return dh.GetPageContext(context.Background(), query, req, args...)
+}
+
+// GetPageContext - gets a page of rows of a query with a context
+func (dh *DataHelper) GetPageContext(ctx context.Context, query string, req PageRequest, args ...interface{}) (*Page, error) {
+
+	if req.Size <= 0 {
+		return nil, ErrNoPageSize
+	}
+
+	keyset := req.Keyset
+	if keyset && (len(req.OrderBy) == 0 || (len(req.After) > 0 && len(req.After) != len(req.OrderBy))) {
+		return nil, fmt.Errorf("%w: %d keys for %d order columns", ErrNoPageOrder, len(req.After), len(req.OrderBy))
+	}
+
+	query = strings.TrimRight(strings.TrimSpace(query), `;`)
+	from := `(` + query + `) pg`
+
+	p := &Page{Total: -1}
+
+	if !keyset || req.CountTotal {
+		cq, cargs := dh.Select(`COUNT(*)`).From(from).Query()
+		dt, err := dh.GetDataContext(ctx, cq, append(args[:len(args):len(args)], cargs...)...)
+		if err != nil {
+			return nil, err
+		}
+		if dt.RowCount > 0 {
+			p.Total = dt.Rows[0].ValueInt64Ord(0)
+		}
+	}
+
+	sb := dh.Select().From(from).OrderBy(req.OrderBy...).Limit(int64(req.Size) + 1)
+
+	if keyset && len(req.After) > 0 {
+		cond, cargs := keysetCondition(dh, req.OrderBy, req.After)
+		sb.Where(cond, cargs...)
+	} else if req.Page > 1 {
+		sb.Offset(int64(req.Page-1) * int64(req.Size))
+	}
+
+	pq, pargs := sb.Query()
+	dt, err := dh.GetDataContext(ctx, pq, append(args[:len(args):len(args)], pargs...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// one more row than the page size was asked for to know if there is a next page
+	if dt.RowCount > req.Size {
+		dt.Rows = dt.Rows[:req.Size]
+		dt.RowCount = req.Size
+		p.HasNext = true
+	}
+
+	p.Data = dt
+
+	if dt.RowCount > 0 && len(req.OrderBy) > 0 {
+		last := dt.Rows[dt.RowCount-1]
+		p.LastKey = make([]interface{}, len(req.OrderBy))
+		for i, o := range req.OrderBy {
+			name, _ := orderColumn(o)
+			if j := strings.LastIndexByte(name, '.'); j != -1 {
+				name = name[j+1:]
+			}
+			for _, c := range last.Cells {
+				if strings.EqualFold(c.ColumnName, strings.Trim(name, "[]\"`")) {
+					p.LastKey[i] = c.Value
+					break
+				}
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// keysetCondition returns the condition for rows after a key, such as
+// a > ? OR (a = ? AND b > ?) for the order a, b. Descending columns compare with <.
+func keysetCondition(dh *DataHelper, orderBy []string, after []interface{}) (string, []interface{}) {
+
+	var (
+		ors  []string
+		args []interface{}
+	)
+
+	for i := range orderBy {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			col, _ := orderColumn(orderBy[j])
+			ands = append(ands, dh.quoteName(col)+` = ?`)
+			args = append(args, after[j])
+		}
+
+		col, desc := orderColumn(orderBy[i])
+		op := ` > ?`
+		if desc {
+			op = ` < ?`
+		}
+		ands = append(ands, dh.quoteName(col)+op)
+		args = append(args, after[i])
+
+		if len(ands) == 1 {
+			ors = append(ors, ands[0])
+		} else {
+			ors = append(ors, `(`+strings.Join(ands, ` AND `)+`)`)
+		}
+	}
+
+	return strings.Join(ors, ` OR `), args
+}
+
+// orderColumn splits an order by item into its column and if it is descending
+func orderColumn(order string) (string, bool) {
+	f := strings.Fields(order)
+	if len(f) == 0 {
+		return "", false
+	}
+	return f[0], len(f) > 1 && strings.EqualFold(f[1], `DESC`)
+}
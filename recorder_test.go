@@ -0,0 +1,103 @@
+package datahelper
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	cfg "github.com/eaglebush/config"
+)
+
+// recorder is a database driver that records the queries sent to it. Queries return one row
+// with the value 1, and those containing the fail text return an error.
+type recorder struct {
+	mu      sync.Mutex
+	queries []string
+	fail    string
+}
+
+func (r *recorder) Connect(context.Context) (driver.Conn, error) { return recorderConn{r}, nil }
+func (r *recorder) Driver() driver.Driver                        { return nil }
+
+// last returns the last query sent
+func (r *recorder) last() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.queries) == 0 {
+		return ""
+	}
+	return r.queries[len(r.queries)-1]
+}
+
+// take returns the queries sent since the last call
+func (r *recorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	q := r.queries
+	r.queries = nil
+	return q
+}
+
+type recorderConn struct{ r *recorder }
+
+func (c recorderConn) Prepare(query string) (driver.Stmt, error) {
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+
+	c.r.queries = append(c.r.queries, query)
+	if c.r.fail != "" && strings.Contains(query, c.r.fail) {
+		return nil, errors.New("recorder: " + c.r.fail + " failed")
+	}
+	return recorderStmt{}, nil
+}
+func (c recorderConn) Close() error              { return nil }
+func (c recorderConn) Begin() (driver.Tx, error) { return recorderTx{}, nil }
+
+type recorderStmt struct{}
+
+func (recorderStmt) Close() error                               { return nil }
+func (recorderStmt) NumInput() int                              { return -1 }
+func (recorderStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(0), nil }
+func (recorderStmt) Query([]driver.Value) (driver.Rows, error)  { return &recorderRows{}, nil }
+
+type recorderRows struct{ done bool }
+
+func (*recorderRows) Columns() []string { return []string{`x`} }
+func (*recorderRows) Close() error      { return nil }
+func (rs *recorderRows) Next(dest []driver.Value) error {
+	if rs.done {
+		return io.EOF
+	}
+	rs.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+type recorderTx struct{}
+
+func (recorderTx) Commit() error   { return nil }
+func (recorderTx) Rollback() error { return nil }
+
+// newRecorderHelper creates a helper of a driver whose queries go to a recorder. The connection
+// string is new on every call, so that sequences cached by connection are not shared between runs.
+func newRecorderHelper(t *testing.T, driverName string, di cfg.DatabaseInfo) (*DataHelper, *recorder) {
+	t.Helper()
+
+	r := &recorder{}
+	db := sql.OpenDB(r)
+	t.Cleanup(func() { db.Close() })
+
+	di.DriverName = driverName
+	return &DataHelper{
+		db:                  db,
+		connectionString:    fmt.Sprintf(`%s_%p`, t.Name(), r),
+		DriverName:          driverName,
+		CurrentDatabaseInfo: &di,
+	}, r
+}
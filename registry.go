@@ -0,0 +1,197 @@
+package datahelper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	cfg "github.com/eaglebush/config"
+)
+
+// Registry - a set of connection pools, opened once per database and shared by the helpers
+// created from it. Only the pool is shared: every helper keeps the database information and
+// settings of the configuration it was created with. Pools stay open until Shutdown, or until
+// ClosePool closes one of them. A Registry is safe for concurrent use.
+type Registry struct {
+	mu    sync.Mutex
+	pools map[registryKey]*registryPool
+}
+
+// registryPool - a pool of the registry. Ready is closed once the pool was opened or failed to.
+type registryPool struct {
+	ready chan struct{}
+	db    *sql.DB
+	err   error
+}
+
+// registryKey identifies a pool by the connection id and what it connects to
+type registryKey struct {
+	ConnectionID     string
+	DriverName       string
+	ConnectionString string
+}
+
+// defaultRegistry is used by NewConnected and Connect
+var defaultRegistry = NewRegistry()
+
+// NewRegistry - creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		pools: make(map[registryKey]*registryPool),
+	}
+}
+
+// DefaultRegistry - returns the process-wide registry used by NewConnected and Connect
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Shutdown - closes every pool of the process-wide registry
+func Shutdown(ctx context.Context) error {
+	return defaultRegistry.Shutdown(ctx)
+}
+
+// Connection - gets a connection on the pool of a database in the configuration, opening the
+// pool on first use. The connection has the database information and settings of this
+// configuration, even if the pool was opened with another one.
+// If no connection id is specified, the default database id of the configuration is used.
+func (r *Registry) Connection(config *cfg.Configuration, ConnectionID ...string) (*Connection, error) {
+
+	if config == nil {
+		return nil, ErrUnknownConnectionID
+	}
+
+	cid := ``
+	if len(ConnectionID) > 0 {
+		cid = ConnectionID[0]
+	}
+
+	c, err := newConnection(cid, config)
+	if err != nil {
+		return nil, err
+	}
+
+	key := c.registryKey()
+
+	// the pool is opened outside of the lock, so that a slow database does not hold up the others
+	r.mu.Lock()
+	p, ok := r.pools[key]
+	if !ok {
+		p = &registryPool{ready: make(chan struct{})}
+		r.pools[key] = p
+	}
+	r.mu.Unlock()
+
+	if !ok {
+		p.db, p.err = c.openDB()
+		if p.err != nil {
+			r.mu.Lock()
+			if r.pools[key] == p {
+				delete(r.pools, key)
+			}
+			r.mu.Unlock()
+		}
+		close(p.ready)
+	}
+
+	<-p.ready
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	c.db = p.db
+	c.registry = r
+	return c, nil
+}
+
+// ClosePool - closes the pool of a database in the configuration without waiting for Shutdown.
+// Helpers and sessions still on the pool could no longer run queries, and the next one asking
+// for the database opens a new pool.
+// If no connection id is specified, the default database id of the configuration is used.
+func (r *Registry) ClosePool(config *cfg.Configuration, ConnectionID ...string) error {
+
+	if config == nil {
+		return ErrUnknownConnectionID
+	}
+
+	cid := ``
+	if len(ConnectionID) > 0 {
+		cid = ConnectionID[0]
+	}
+
+	c, err := newConnection(cid, config)
+	if err != nil {
+		return err
+	}
+
+	key := c.registryKey()
+
+	r.mu.Lock()
+	p, ok := r.pools[key]
+	if ok {
+		delete(r.pools, key)
+	}
+	r.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	<-p.ready
+	if p.db == nil {
+		return nil
+	}
+	return p.db.Close()
+}
+
+// registryKey gets the key of the pool of the connection in a registry
+func (c *Connection) registryKey() registryKey {
+	return registryKey{
+		ConnectionID:     c.ConnectionID,
+		DriverName:       c.DriverName,
+		ConnectionString: c.connectionString,
+	}
+}
+
+// Session - creates a session on the pool of a database in the configuration
+func (r *Registry) Session(config *cfg.Configuration, ConnectionID ...string) (*Session, error) {
+	c, err := r.Connection(config, ConnectionID...)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Session(), nil
+}
+
+// Shutdown - closes every pool of the registry. Closing waits for running queries to finish,
+// until the context is done. Pools requested after a shutdown are opened again.
+func (r *Registry) Shutdown(ctx context.Context) error {
+
+	r.mu.Lock()
+	pools := r.pools
+	r.pools = make(map[registryKey]*registryPool)
+	r.mu.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		var errs []error
+		for _, p := range pools {
+			<-p.ready
+			if p.db == nil {
+				continue
+			}
+			if err := p.db.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		done <- errors.Join(errs...)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,129 @@
+package datahelper
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+
+	"github.com/eaglebush/datatable"
+)
+
+// InsertReturning - runs an INSERT query and returns the values of the returning columns of the
+// inserted row, such as generated keys. The query is written with ? parameters and {table}
+// placeholders like in Exec.
+//
+// SQL Server gets an OUTPUT INSERTED clause, Postgres and SQLite 3.35 or later a RETURNING clause.
+// MySQL could only return the generated key through LastInsertId, which is put in the first
+// returning column.
+func (dh *DataHelper) InsertReturning(query string, returningColumns []string, args ...interface{}) (datatable.Row, error) {
+	return dh.InsertReturningContext(context.Background(), query, returningColumns, args...)
+}
+
+// InsertReturningContext - runs an INSERT query with a context and returns the values of the returning columns
+func (dh *DataHelper) InsertReturningContext(ctx context.Context, query string, returningColumns []string, args ...interface{}) (datatable.Row, error) {
+
+	if len(returningColumns) == 0 {
+		return datatable.Row{}, ErrNoColumn
+	}
+
+	query = strings.TrimRight(strings.TrimSpace(query), `;`)
+
+	switch dh.dialect().Name() {
+	case `mysql`:
+		res, err := dh.ExecContext(ctx, query, args...)
+		if err != nil {
+			return datatable.Row{}, err
+		}
+
+		id, err := res.LastInsertId()
+		if err != nil {
+			return datatable.Row{}, err
+		}
+
+		dt := datatable.NewDataTable("data")
+		dt.AddColumn(returningColumns[0], reflect.TypeOf(id), 0, `BIGINT`)
+		r := dt.NewRow()
+		r.Cells[0].Value = id
+		dt.AddRow(&r)
+
+		return dt.Rows[0], nil
+
+	case `sqlserver`:
+		cols := make([]string, len(returningColumns))
+		for i, c := range returningColumns {
+			cols[i] = `INSERTED.` + dh.quoteName(c)
+		}
+
+		q, ok := insertOutputClause(query, `OUTPUT `+strings.Join(cols, `, `)+` `, dh.syntax())
+		if !ok {
+			return datatable.Row{}, ErrNoOutputPosition
+		}
+		query = q
+
+	default:
+		query += ` RETURNING ` + dh.quoteNames(returningColumns)
+	}
+
+	dt, err := dh.GetDataContext(ctx, query, args...)
+	if err != nil {
+		return datatable.Row{}, err
+	}
+
+	if dt.RowCount == 0 {
+		return datatable.Row{}, sql.ErrNoRows
+	}
+
+	return dt.Rows[0], nil
+}
+
+// insertOutputClause puts an OUTPUT clause before the VALUES, SELECT, DEFAULT VALUES or EXEC of an INSERT.
+// Literals, comments and parenthesized parts, like the column list, are skipped.
+func insertOutputClause(query, output string, syn sqlSyntax) (string, bool) {
+
+	var (
+		sb    strings.Builder
+		depth int
+		done  bool
+	)
+
+	for _, t := range tokenizeSQL(query, syn) {
+		switch t.Kind {
+		case tokPlaceholder:
+			t.Text = `{` + t.Text + `}`
+		case tokNamedParam:
+			t.Text = `:` + t.Text
+		}
+
+		if done || t.Kind != tokText {
+			sb.WriteString(t.Text)
+			continue
+		}
+
+		s := t.Text
+		for i := 0; i < len(s); i++ {
+			switch c := s[i]; {
+			case c == '(':
+				depth++
+			case c == ')':
+				depth--
+			case depth == 0 && !done && isNameStart(c) && (i == 0 || !isNameChar(s[i-1])):
+				j := i
+				for j < len(s) && isNameChar(s[j]) {
+					j++
+				}
+				switch strings.ToUpper(s[i:j]) {
+				case `VALUES`, `SELECT`, `DEFAULT`, `EXEC`, `EXECUTE`:
+					sb.WriteString(output)
+					done = true
+				}
+				sb.WriteString(s[i:j])
+				i = j - 1
+				continue
+			}
+			sb.WriteByte(s[i])
+		}
+	}
+
+	return sb.String(), done
+}
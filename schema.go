@@ -0,0 +1,447 @@
+package datahelper
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// ColumnInfo - metadata of a table column
+type ColumnInfo struct {
+	Name      string  `db:"column_name"`
+	DBType    string  `db:"db_type"`       // Type name in the database, without length or precision
+	Length    int64   `db:"column_length"` // Maximum length of character and binary types. -1 for MAX types, 0 if not applicable.
+	Precision int64   `db:"num_precision"` // Precision of numeric types, 0 if not applicable
+	Scale     int64   `db:"num_scale"`     // Scale of numeric types, 0 if not applicable
+	Nullable  bool    `db:"is_nullable"`
+	Default   *string `db:"default_value"` // Default expression, nil if the column has none
+	Identity  bool    `db:"is_identity"`   // Value is generated by the database, like IDENTITY, serial or INTEGER PRIMARY KEY
+}
+
+// ForeignKeyInfo - metadata of a foreign key
+type ForeignKeyInfo struct {
+	Name       string
+	Columns    []string // Columns of the table, in key order
+	RefSchema  string   // Schema of the referenced table
+	RefTable   string   // Referenced table
+	RefColumns []string // Referenced columns, in the order of Columns
+}
+
+// IndexInfo - metadata of an index
+type IndexInfo struct {
+	Name    string
+	Columns []string // Key columns, in key order
+	Unique  bool
+	Primary bool // Index of the primary key
+}
+
+// keyColumnRow - a column of a named key or index as returned by the catalog queries
+type keyColumnRow struct {
+	Name      string `db:"key_name"`
+	Column    string `db:"column_name"`
+	RefSchema string `db:"ref_schema"`
+	RefTable  string `db:"ref_table"`
+	RefColumn string `db:"ref_column"`
+	Unique    bool   `db:"is_unique"`
+	Primary   bool   `db:"is_primary"`
+}
+
+// Tables - gets the names of the tables in the configured schema
+func (dh *DataHelper) Tables() ([]string, error) {
+	return dh.TablesContext(context.Background())
+}
+
+// TablesContext - gets the names of the tables in the configured schema with a context
+func (dh *DataHelper) TablesContext(ctx context.Context) ([]string, error) {
+
+	schema, _ := dh.schemaTable("")
+
+	switch dh.dialect().Name() {
+	case `sqlserver`:
+		return SelectContext[string](ctx, dh, `SELECT TABLE_NAME FROM INFORMATION_SCHEMA.TABLES
+			WHERE TABLE_TYPE = 'BASE TABLE' AND TABLE_SCHEMA = ?
+			ORDER BY TABLE_NAME;`, schema)
+
+	case `postgres`:
+		return SelectContext[string](ctx, dh, `SELECT c.relname FROM pg_catalog.pg_class c
+			JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+			WHERE c.relkind IN ('r', 'p') AND n.nspname = ?
+			ORDER BY c.relname;`, schema)
+
+	case `sqlite3`:
+		return SelectContext[string](ctx, dh, `SELECT name FROM `+dh.dialect().QuoteIdentifier(schema)+`.sqlite_master
+			WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+			ORDER BY name;`)
+	}
+
+	return nil, ErrNoIntrospection
+}
+
+// Columns - gets the columns of a table in their ordinal order. The table could be a {table}
+// placeholder or have a schema. Without a schema, the configured schema is used.
+func (dh *DataHelper) Columns(table string) ([]ColumnInfo, error) {
+	return dh.ColumnsContext(context.Background(), table)
+}
+
+// ColumnsContext - gets the columns of a table with a context
+func (dh *DataHelper) ColumnsContext(ctx context.Context, table string) ([]ColumnInfo, error) {
+
+	schema, name := dh.schemaTable(table)
+	if name == "" {
+		return nil, ErrNoTableName
+	}
+
+	switch dh.dialect().Name() {
+	case `sqlserver`:
+		return SelectContext[ColumnInfo](ctx, dh, `SELECT c.COLUMN_NAME AS column_name, c.DATA_TYPE AS db_type,
+				COALESCE(c.CHARACTER_MAXIMUM_LENGTH, 0) AS column_length,
+				COALESCE(c.NUMERIC_PRECISION, 0) AS num_precision,
+				COALESCE(c.NUMERIC_SCALE, 0) AS num_scale,
+				CASE WHEN c.IS_NULLABLE = 'YES' THEN 1 ELSE 0 END AS is_nullable,
+				c.COLUMN_DEFAULT AS default_value,
+				COALESCE(COLUMNPROPERTY(OBJECT_ID(QUOTENAME(c.TABLE_SCHEMA) + '.' + QUOTENAME(c.TABLE_NAME)), c.COLUMN_NAME, 'IsIdentity'), 0) AS is_identity
+			FROM INFORMATION_SCHEMA.COLUMNS c
+			WHERE c.TABLE_SCHEMA = ? AND c.TABLE_NAME = ?
+			ORDER BY c.ORDINAL_POSITION;`, schema, name)
+
+	case `postgres`:
+		// character lengths and numeric precisions are packed in atttypmod with a 4 byte header
+		return SelectContext[ColumnInfo](ctx, dh, `SELECT a.attname AS column_name,
+				pg_catalog.format_type(a.atttypid, NULL) AS db_type,
+				CASE WHEN a.atttypid IN (1042, 1043) AND a.atttypmod > 0 THEN a.atttypmod - 4 ELSE 0 END AS column_length,
+				CASE WHEN a.atttypid = 1700 AND a.atttypmod > 0 THEN ((a.atttypmod - 4) >> 16) & 65535 ELSE 0 END AS num_precision,
+				CASE WHEN a.atttypid = 1700 AND a.atttypmod > 0 THEN (a.atttypmod - 4) & 65535 ELSE 0 END AS num_scale,
+				NOT a.attnotnull AS is_nullable,
+				pg_catalog.pg_get_expr(d.adbin, d.adrelid) AS default_value,
+				(a.attidentity <> '' OR COALESCE(pg_catalog.pg_get_expr(d.adbin, d.adrelid), '') LIKE 'nextval(%') AS is_identity
+			FROM pg_catalog.pg_attribute a
+			JOIN pg_catalog.pg_class c ON c.oid = a.attrelid
+			JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+			LEFT JOIN pg_catalog.pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+			WHERE n.nspname = ? AND c.relname = ? AND a.attnum > 0 AND NOT a.attisdropped
+			ORDER BY a.attnum;`, schema, name)
+
+	case `sqlite3`:
+		return dh.sqliteColumns(ctx, schema, name)
+	}
+
+	return nil, ErrNoIntrospection
+}
+
+// PrimaryKey - gets the primary key columns of a table in key order
+func (dh *DataHelper) PrimaryKey(table string) ([]string, error) {
+	return dh.PrimaryKeyContext(context.Background(), table)
+}
+
+// PrimaryKeyContext - gets the primary key columns of a table with a context
+func (dh *DataHelper) PrimaryKeyContext(ctx context.Context, table string) ([]string, error) {
+
+	schema, name := dh.schemaTable(table)
+	if name == "" {
+		return nil, ErrNoTableName
+	}
+
+	switch dh.dialect().Name() {
+	case `sqlserver`:
+		return SelectContext[string](ctx, dh, `SELECT kcu.COLUMN_NAME
+			FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc
+			JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu ON kcu.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA AND kcu.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+			WHERE tc.CONSTRAINT_TYPE = 'PRIMARY KEY' AND tc.TABLE_SCHEMA = ? AND tc.TABLE_NAME = ?
+			ORDER BY kcu.ORDINAL_POSITION;`, schema, name)
+
+	case `postgres`:
+		return SelectContext[string](ctx, dh, `SELECT a.attname
+			FROM pg_catalog.pg_index i
+			JOIN pg_catalog.pg_class c ON c.oid = i.indrelid
+			JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+			JOIN LATERAL unnest(i.indkey) WITH ORDINALITY AS k(attnum, ord) ON true
+			JOIN pg_catalog.pg_attribute a ON a.attrelid = c.oid AND a.attnum = k.attnum
+			WHERE i.indisprimary AND n.nspname = ? AND c.relname = ?
+			ORDER BY k.ord;`, schema, name)
+
+	case `sqlite3`:
+		dt, err := dh.GetDataContext(ctx, `PRAGMA `+dh.sqlitePragmaTarget(schema, `table_info`, name)+`;`)
+		if err != nil {
+			return nil, err
+		}
+
+		// pk is the 1-based position of the column in the key, 0 if it is not a key column
+		cols := make([]string, dt.RowCount)
+		n := 0
+		for _, r := range dt.Rows {
+			if pk := r.ValueInt64Ord(5); pk > 0 && int(pk) <= len(cols) {
+				cols[pk-1] = r.ValueStringOrd(1)
+				n++
+			}
+		}
+		return cols[:n], nil
+	}
+
+	return nil, ErrNoIntrospection
+}
+
+// ForeignKeys - gets the foreign keys of a table
+func (dh *DataHelper) ForeignKeys(table string) ([]ForeignKeyInfo, error) {
+	return dh.ForeignKeysContext(context.Background(), table)
+}
+
+// ForeignKeysContext - gets the foreign keys of a table with a context
+func (dh *DataHelper) ForeignKeysContext(ctx context.Context, table string) ([]ForeignKeyInfo, error) {
+
+	schema, name := dh.schemaTable(table)
+	if name == "" {
+		return nil, ErrNoTableName
+	}
+
+	var (
+		rows []keyColumnRow
+		err  error
+	)
+
+	switch dh.dialect().Name() {
+	case `sqlserver`:
+		rows, err = SelectContext[keyColumnRow](ctx, dh, `SELECT fk.name AS key_name, pc.name AS column_name,
+				SCHEMA_NAME(rt.schema_id) AS ref_schema, rt.name AS ref_table, rc.name AS ref_column
+			FROM sys.foreign_keys fk
+			JOIN sys.foreign_key_columns fkc ON fkc.constraint_object_id = fk.object_id
+			JOIN sys.columns pc ON pc.object_id = fkc.parent_object_id AND pc.column_id = fkc.parent_column_id
+			JOIN sys.tables rt ON rt.object_id = fkc.referenced_object_id
+			JOIN sys.columns rc ON rc.object_id = fkc.referenced_object_id AND rc.column_id = fkc.referenced_column_id
+			WHERE fk.parent_object_id = OBJECT_ID(?)
+			ORDER BY fk.name, fkc.constraint_column_id;`, dh.dialect().QuoteIdentifier(schema)+`.`+dh.dialect().QuoteIdentifier(name))
+
+	case `postgres`:
+		rows, err = SelectContext[keyColumnRow](ctx, dh, `SELECT con.conname AS key_name, a.attname AS column_name,
+				rn.nspname AS ref_schema, rc.relname AS ref_table, ra.attname AS ref_column
+			FROM pg_catalog.pg_constraint con
+			JOIN pg_catalog.pg_class c ON c.oid = con.conrelid
+			JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+			JOIN pg_catalog.pg_class rc ON rc.oid = con.confrelid
+			JOIN pg_catalog.pg_namespace rn ON rn.oid = rc.relnamespace
+			JOIN LATERAL unnest(con.conkey, con.confkey) WITH ORDINALITY AS k(attnum, refnum, ord) ON true
+			JOIN pg_catalog.pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+			JOIN pg_catalog.pg_attribute ra ON ra.attrelid = con.confrelid AND ra.attnum = k.refnum
+			WHERE con.contype = 'f' AND n.nspname = ? AND c.relname = ?
+			ORDER BY con.conname, k.ord;`, schema, name)
+
+	case `sqlite3`:
+		// foreign keys have no names in SQLite, they are numbered by id
+		dt, derr := dh.GetDataContext(ctx, `PRAGMA `+dh.sqlitePragmaTarget(schema, `foreign_key_list`, name)+`;`)
+		if derr != nil {
+			return nil, derr
+		}
+		for _, r := range dt.Rows {
+			rows = append(rows, keyColumnRow{
+				Name:      `fk_` + strconv.FormatInt(r.ValueInt64Ord(0), 10),
+				Column:    r.ValueStringOrd(3),
+				RefSchema: schema,
+				RefTable:  r.ValueStringOrd(2),
+				RefColumn: r.ValueStringOrd(4),
+			})
+		}
+
+	default:
+		return nil, ErrNoIntrospection
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var fks []ForeignKeyInfo
+	for _, r := range rows {
+		if n := len(fks); n == 0 || fks[n-1].Name != r.Name {
+			fks = append(fks, ForeignKeyInfo{
+				Name:      r.Name,
+				RefSchema: r.RefSchema,
+				RefTable:  r.RefTable,
+			})
+		}
+		fk := &fks[len(fks)-1]
+		fk.Columns = append(fk.Columns, r.Column)
+		fk.RefColumns = append(fk.RefColumns, r.RefColumn)
+	}
+
+	return fks, nil
+}
+
+// Indexes - gets the indexes of a table
+func (dh *DataHelper) Indexes(table string) ([]IndexInfo, error) {
+	return dh.IndexesContext(context.Background(), table)
+}
+
+// IndexesContext - gets the indexes of a table with a context
+func (dh *DataHelper) IndexesContext(ctx context.Context, table string) ([]IndexInfo, error) {
+
+	schema, name := dh.schemaTable(table)
+	if name == "" {
+		return nil, ErrNoTableName
+	}
+
+	var (
+		rows []keyColumnRow
+		err  error
+	)
+
+	switch dh.dialect().Name() {
+	case `sqlserver`:
+		rows, err = SelectContext[keyColumnRow](ctx, dh, `SELECT i.name AS key_name, c.name AS column_name,
+				i.is_unique AS is_unique, i.is_primary_key AS is_primary
+			FROM sys.indexes i
+			JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+			JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+			WHERE i.object_id = OBJECT_ID(?) AND i.name IS NOT NULL AND ic.is_included_column = 0
+			ORDER BY i.name, ic.key_ordinal;`, dh.dialect().QuoteIdentifier(schema)+`.`+dh.dialect().QuoteIdentifier(name))
+
+	case `postgres`:
+		rows, err = SelectContext[keyColumnRow](ctx, dh, `SELECT ic.relname AS key_name, a.attname AS column_name,
+				i.indisunique AS is_unique, i.indisprimary AS is_primary
+			FROM pg_catalog.pg_index i
+			JOIN pg_catalog.pg_class c ON c.oid = i.indrelid
+			JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+			JOIN pg_catalog.pg_class ic ON ic.oid = i.indexrelid
+			JOIN LATERAL unnest(i.indkey) WITH ORDINALITY AS k(attnum, ord) ON true
+			JOIN pg_catalog.pg_attribute a ON a.attrelid = c.oid AND a.attnum = k.attnum
+			WHERE n.nspname = ? AND c.relname = ?
+			ORDER BY ic.relname, k.ord;`, schema, name)
+
+	case `sqlite3`:
+		dt, derr := dh.GetDataContext(ctx, `PRAGMA `+dh.sqlitePragmaTarget(schema, `index_list`, name)+`;`)
+		if derr != nil {
+			return nil, derr
+		}
+		for _, r := range dt.Rows {
+			iname := r.ValueStringOrd(1)
+			cols, cerr := dh.GetDataContext(ctx, `PRAGMA `+dh.sqlitePragmaTarget(schema, `index_info`, iname)+`;`)
+			if cerr != nil {
+				return nil, cerr
+			}
+			for _, c := range cols.Rows {
+				rows = append(rows, keyColumnRow{
+					Name:    iname,
+					Column:  c.ValueStringOrd(2),
+					Unique:  r.ValueInt64Ord(2) != 0,
+					Primary: r.ValueStringOrd(3) == `pk`,
+				})
+			}
+		}
+
+	default:
+		return nil, ErrNoIntrospection
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var idxs []IndexInfo
+	for _, r := range rows {
+		if n := len(idxs); n == 0 || idxs[n-1].Name != r.Name {
+			idxs = append(idxs, IndexInfo{
+				Name:    r.Name,
+				Unique:  r.Unique,
+				Primary: r.Primary,
+			})
+		}
+		idx := &idxs[len(idxs)-1]
+		idx.Columns = append(idx.Columns, r.Column)
+	}
+
+	return idxs, nil
+}
+
+// sqliteColumns reads the columns of a SQLite table from PRAGMA table_info
+func (dh *DataHelper) sqliteColumns(ctx context.Context, schema, name string) ([]ColumnInfo, error) {
+
+	dt, err := dh.GetDataContext(ctx, `PRAGMA `+dh.sqlitePragmaTarget(schema, `table_info`, name)+`;`)
+	if err != nil {
+		return nil, err
+	}
+
+	pks := 0
+	for _, r := range dt.Rows {
+		if r.ValueInt64Ord(5) > 0 {
+			pks++
+		}
+	}
+
+	cols := make([]ColumnInfo, 0, dt.RowCount)
+	for _, r := range dt.Rows {
+		ci := ColumnInfo{
+			Name:     r.ValueStringOrd(1),
+			Nullable: r.ValueInt64Ord(3) == 0,
+			Default:  r.ValuePtrStringOrd(4),
+		}
+
+		// declared types carry their size, such as VARCHAR(50) or DECIMAL(10, 2)
+		ci.DBType = r.ValueStringOrd(2)
+		if i := strings.IndexByte(ci.DBType, '('); i != -1 {
+			size := strings.Split(strings.TrimSuffix(ci.DBType[i+1:], `)`), `,`)
+			ci.DBType = strings.TrimSpace(ci.DBType[:i])
+			p, _ := strconv.ParseInt(strings.TrimSpace(size[0]), 10, 64)
+			if len(size) > 1 {
+				ci.Precision = p
+				ci.Scale, _ = strconv.ParseInt(strings.TrimSpace(size[1]), 10, 64)
+			} else {
+				ci.Length = p
+			}
+		}
+
+		// a lone INTEGER PRIMARY KEY is the rowid and is generated
+		ci.Identity = pks == 1 && r.ValueInt64Ord(5) == 1 && strings.EqualFold(ci.DBType, `INTEGER`)
+		if ci.Identity {
+			ci.Nullable = false
+		}
+
+		cols = append(cols, ci)
+	}
+
+	return cols, nil
+}
+
+// sqlitePragmaTarget returns the schema qualified pragma call on a table or index
+func (dh *DataHelper) sqlitePragmaTarget(schema, pragma, name string) string {
+	d := dh.dialect()
+	return d.QuoteIdentifier(schema) + `.` + pragma + `(` + d.QuoteIdentifier(name) + `)`
+}
+
+// schemaTable splits a table name into its schema and name. A {table} placeholder loses its
+// braces and quotes are removed. Without a schema, the configured schema or the default
+// schema of the database is used. Unquoted Postgres names are folded to lower case.
+func (dh *DataHelper) schemaTable(table string) (string, string) {
+
+	table = strings.TrimSpace(strings.Trim(strings.TrimSpace(table), `{}`))
+
+	schema := ""
+	if i := strings.LastIndexByte(table, '.'); i != -1 {
+		schema, table = table[:i], table[i+1:]
+	}
+
+	if schema == "" && dh.CurrentDatabaseInfo != nil {
+		schema = strings.TrimSuffix(dh.CurrentDatabaseInfo.Schema, `.`)
+	}
+
+	name := dh.dialect().Name()
+	if schema == "" {
+		switch name {
+		case `sqlserver`:
+			schema = `dbo`
+		case `postgres`:
+			schema = `public`
+		case `sqlite3`:
+			schema = `main`
+		}
+	}
+
+	unquote := func(s string) string {
+		if len(s) >= 2 && strings.ContainsRune("[\"`", rune(s[0])) {
+			return s[1 : len(s)-1]
+		}
+		if name == `postgres` {
+			return strings.ToLower(s)
+		}
+		return s
+	}
+
+	return unquote(schema), unquote(table)
+}
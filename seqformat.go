@@ -0,0 +1,193 @@
+package datahelper
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResetPolicy - how often the counter of a formatted sequence starts again from 1
+type ResetPolicy int
+
+// Constants
+const (
+	ResetNever   ResetPolicy = 0 // The counter never resets
+	ResetYearly  ResetPolicy = 1 // A counter per year
+	ResetMonthly ResetPolicy = 2 // A counter per month
+	ResetDaily   ResetPolicy = 3 // A counter per day
+)
+
+var (
+	resetMu       sync.RWMutex
+	resetPolicies = map[string]ResetPolicy{}
+)
+
+// SetSequenceReset - sets how often the counter of a sequence key used by GetFormattedSequence
+// resets. A period has its own counter, kept under the sequence key with the period appended,
+// like INVOICE_2026, INVOICE_202610 or INVOICE_20261017. The counters are rows of the key
+// generator table, or of the configured SequenceGenerator queries, even on databases with
+// native sequences, so that periods do not leave a sequence each behind.
+func SetSequenceReset(sequenceKey string, policy ResetPolicy) {
+	resetMu.Lock()
+	defer resetMu.Unlock()
+
+	if policy == ResetNever {
+		delete(resetPolicies, strings.ToLower(sequenceKey))
+		return
+	}
+	resetPolicies[strings.ToLower(sequenceKey)] = policy
+}
+
+// periodSequenceKey returns the sequence key of the period of a time under the reset policy of a key
+func periodSequenceKey(sequenceKey string, t time.Time) string {
+	resetMu.RLock()
+	policy := resetPolicies[strings.ToLower(sequenceKey)]
+	resetMu.RUnlock()
+
+	switch policy {
+	case ResetYearly:
+		return sequenceKey + `_` + t.Format(`2006`)
+	case ResetMonthly:
+		return sequenceKey + `_` + t.Format(`200601`)
+	case ResetDaily:
+		return sequenceKey + `_` + t.Format(`20060102`)
+	}
+	return sequenceKey
+}
+
+// GetFormattedSequence - get the next sequence based on the sequence key, written with a format
+// such as INV-{yyyy}-{seq:6} for INV-2026-000123. The format could have:
+//
+//	{seq}    - the sequence number
+//	{seq:N}  - the sequence number padded with zeroes to N digits
+//	{yyyy}   - 4 digit year
+//	{yy}     - 2 digit year
+//	{MM}     - 2 digit month
+//	{dd}     - 2 digit day
+//	{check}  - Luhn check digit of all the digits written before it
+//	{{, }}   - literal braces
+//
+// Date parts are of the current local time. See SetSequenceReset to restart the counter every period.
+func (dh *DataHelper) GetFormattedSequence(SequenceKey string, format string) (string, error) {
+	return dh.GetFormattedSequenceContext(context.Background(), SequenceKey, format)
+}
+
+// GetFormattedSequenceContext - get the next formatted sequence based on the sequence key with a context
+func (dh *DataHelper) GetFormattedSequenceContext(ctx context.Context, SequenceKey string, format string) (string, error) {
+
+	// the format is checked before a number is used
+	if _, err := formatSequence(format, 0, time.Time{}); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	key := periodSequenceKey(SequenceKey, now)
+
+	sq, ok, err := dh.nextSequence(ctx, key, key != SequenceKey)
+	if err == nil && !ok {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return formatSequence(format, sq, now)
+}
+
+// formatSequence writes a sequence number with a format
+func formatSequence(format string, n int64, t time.Time) (string, error) {
+
+	var sb strings.Builder
+
+	hasSeq := false
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+
+		if (c == '{' || c == '}') && i+1 < len(format) && format[i+1] == c {
+			sb.WriteByte(c)
+			i++
+			continue
+		}
+
+		if c == '}' {
+			return "", fmt.Errorf("%w: unmatched } at %d", ErrInvalidSequenceFormat, i)
+		}
+
+		if c != '{' {
+			sb.WriteByte(c)
+			continue
+		}
+
+		j := strings.IndexByte(format[i:], '}')
+		if j == -1 {
+			return "", fmt.Errorf("%w: unmatched { at %d", ErrInvalidSequenceFormat, i)
+		}
+
+		tok := format[i+1 : i+j]
+		i += j
+
+		name, arg, hasArg := strings.Cut(tok, `:`)
+		switch {
+		case name == `seq`:
+			s := strconv.FormatInt(n, 10)
+			if hasArg {
+				w, err := strconv.Atoi(arg)
+				if err != nil || w < 1 || w > 20 {
+					return "", fmt.Errorf("%w: {%s}", ErrInvalidSequenceFormat, tok)
+				}
+				if len(s) < w {
+					s = strings.Repeat(`0`, w-len(s)) + s
+				}
+			}
+			sb.WriteString(s)
+			hasSeq = true
+		case hasArg:
+			return "", fmt.Errorf("%w: {%s}", ErrInvalidSequenceFormat, tok)
+		case name == `yyyy`:
+			sb.WriteString(t.Format(`2006`))
+		case name == `yy`:
+			sb.WriteString(t.Format(`06`))
+		case name == `MM`:
+			sb.WriteString(t.Format(`01`))
+		case name == `dd`:
+			sb.WriteString(t.Format(`02`))
+		case name == `check`:
+			sb.WriteByte(luhnCheckDigit(sb.String()))
+		default:
+			return "", fmt.Errorf("%w: {%s}", ErrInvalidSequenceFormat, tok)
+		}
+	}
+
+	if !hasSeq {
+		return "", fmt.Errorf("%w: {seq} is required", ErrInvalidSequenceFormat)
+	}
+
+	return sb.String(), nil
+}
+
+// luhnCheckDigit computes the Luhn check digit of the digits in a string. Other characters are skipped.
+func luhnCheckDigit(s string) byte {
+
+	sum := 0
+	double := true
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+
+		d := int(s[i] - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,64 @@
+package datahelper
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	cfg "github.com/eaglebush/config"
+)
+
+func TestFormatSequence(t *testing.T) {
+	ts := time.Date(2026, 3, 7, 10, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{`INV-{yyyy}-{seq:6}`, `INV-2026-000123`},
+		{`PL{yy}{MM}{dd}-{seq}`, `PL260307-123`},
+		{`{seq:2}`, `123`},
+		{`{{{seq}}}`, `{123}`},
+		{`{seq:4}{check}`, `01230`},
+	}
+
+	for _, tc := range tests {
+		got, err := formatSequence(tc.format, 123, ts)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %q, %v, want %q", tc.format, got, err, tc.want)
+		}
+	}
+
+	// a known Luhn number
+	if got, err := formatSequence(`{seq}{check}`, 7992739871, ts); err != nil || got != `79927398713` {
+		t.Errorf("got %q, %v", got, err)
+	}
+
+	for _, f := range []string{`INV-{yyyy}`, `{seq:x}`, `{seq`, `{week}`, `{yyyy:2}{seq}`, `}{seq}`} {
+		if _, err := formatSequence(f, 1, ts); !errors.Is(err, ErrInvalidSequenceFormat) {
+			t.Errorf("%s: expected ErrInvalidSequenceFormat, got %v", f, err)
+		}
+	}
+}
+
+func TestFormattedSequenceCounterTable(t *testing.T) {
+	SetSequenceReset(`RECEIPT`, ResetMonthly)
+	defer SetSequenceReset(`RECEIPT`, ResetNever)
+
+	for _, driver := range []string{`postgres`, `sqlserver`} {
+		dh, rec := newRecorderHelper(t, driver, cfg.DatabaseInfo{ID: `DEFAULT`})
+		dh.ConnectionID = `DEFAULT`
+		dh.Settings = cfg.Configuration{Databases: &[]cfg.DatabaseInfo{*dh.CurrentDatabaseInfo}}
+
+		if _, err := dh.GetFormattedSequence(`RECEIPT`, `RC{yy}{MM}-{seq:5}`); err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+
+		// period counters are rows of the key generator table, not sequences
+		queries := strings.Join(rec.take(), "\n")
+		if strings.Contains(queries, `SEQUENCE`) || !strings.Contains(queries, `KEYGENERATOR`) {
+			t.Fatalf("%s: unexpected queries %s", driver, queries)
+		}
+	}
+}
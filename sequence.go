@@ -0,0 +1,346 @@
+package datahelper
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// KeyGeneratorTable - table of the sequences of databases without native sequences, and of the
+// period counters of formatted sequences on every database
+const KeyGeneratorTable = `KEYGENERATOR`
+
+// createdSequences records the sequences and key generator tables already created, by connection
+var createdSequences sync.Map
+
+// nativeSequence gets the next value of a sequence with the strategy of the dialect
+func (dh *DataHelper) nativeSequence(ctx context.Context, sequenceKey string) (int64, error) {
+
+	v, err := dh.reserveSequence(ctx, sequenceKey, 1)
+	if err != nil {
+		return 0, err
+	}
+
+	return v[0], nil
+}
+
+// reserveSequence takes n values of a sequence at once with the strategy of the dialect, in
+// ascending order. Sequences and the key generator table are created on first use.
+//
+//	postgres  - CREATE SEQUENCE IF NOT EXISTS and nextval
+//	sqlserver - CREATE SEQUENCE and sp_sequence_get_range
+//	others    - a KEYGENERATOR table with a row per sequence
+//
+// Native sequences are created with an increment of 1 and never altered, so that processes
+// taking single values and blocks of any size can share them. The values a postgres sequence
+// gives in one call could be interleaved with those given to other sessions.
+func (dh *DataHelper) reserveSequence(ctx context.Context, sequenceKey string, n int64) ([]int64, error) {
+
+	schema := ""
+	if dh.CurrentDatabaseInfo != nil && dh.CurrentDatabaseInfo.Schema != "" {
+		schema = strings.TrimSuffix(dh.CurrentDatabaseInfo.Schema, `.`) + `.`
+	}
+
+	var (
+		create string
+		cargs  []interface{}
+		next   string
+	)
+
+	seq := schema + `seq_` + sequenceKey
+
+	switch dh.dialect().Name() {
+	case `postgres`:
+		create = `CREATE SEQUENCE IF NOT EXISTS ` + seq + ` START WITH 1 INCREMENT BY 1;`
+		next = `SELECT nextval(?) FROM generate_series(1, ?);`
+
+	case `sqlserver`:
+		create = `IF OBJECT_ID(?, 'SO') IS NULL CREATE SEQUENCE ` + seq + ` AS BIGINT START WITH 1 INCREMENT BY 1;`
+		cargs = []interface{}{seq}
+		next = `SET NOCOUNT ON;
+			DECLARE @first SQL_VARIANT;
+			EXEC sys.sp_sequence_get_range @sequence_name = ?, @range_size = ?, @range_first_value = @first OUTPUT;
+			SELECT CAST(@first AS BIGINT);`
+
+	default:
+		hi, err := dh.keyGeneratorSequence(ctx, sequenceKey, n)
+		if err != nil {
+			return nil, err
+		}
+		return sequenceRange(hi-n+1, n), nil
+	}
+
+	if err := dh.createSequenceObject(ctx, seq, create, cargs...); err != nil {
+		return nil, err
+	}
+
+	dt, err := dh.GetDataContext(ctx, next, seq, n)
+	if err != nil {
+		return nil, err
+	}
+
+	if dt.RowCount == 0 {
+		return nil, fmt.Errorf("Sequence %s returned no value", seq)
+	}
+
+	if dh.dialect().Name() == `sqlserver` {
+		return sequenceRange(dt.Rows[0].ValueInt64Ord(0), n), nil
+	}
+
+	v := make([]int64, 0, dt.RowCount)
+	for _, r := range dt.Rows {
+		v = append(v, r.ValueInt64Ord(0))
+	}
+	sort.Slice(v, func(i, j int) bool { return v[i] < v[j] })
+
+	return v, nil
+}
+
+// sequenceRange returns n values starting from first
+func sequenceRange(first, n int64) []int64 {
+	v := make([]int64, n)
+	for i := range v {
+		v[i] = first + int64(i)
+	}
+	return v
+}
+
+// createSequenceObject runs the DDL creating a sequence or the key generator table, once per
+// connection. DDL run in a transaction is rolled back with it, so there it is only recorded
+// as done when it runs again outside of one.
+func (dh *DataHelper) createSequenceObject(ctx context.Context, name, ddl string, args ...interface{}) error {
+
+	ck := dh.DriverName + "\x00" + dh.connectionString + "\x00" + name
+	if _, ok := createdSequences.Load(ck); ok {
+		return nil
+	}
+
+	if _, err := dh.ExecContext(ctx, ddl, args...); err != nil {
+		return err
+	}
+
+	if dh.tx == nil {
+		createdSequences.Store(ck, struct{}{})
+	}
+
+	return nil
+}
+
+// keyGeneratorSequence increments the row of a sequence in the key generator table and reads it
+// back in the same transaction, so that concurrent sessions never get the same value
+func (dh *DataHelper) keyGeneratorSequence(ctx context.Context, sequenceKey string, n int64) (value int64, err error) {
+
+	table := `{` + KeyGeneratorTable + `}`
+
+	var (
+		create string
+		cargs  []interface{}
+		upsert string
+	)
+
+	columns := ` (
+			SequenceName VARCHAR(85) NOT NULL PRIMARY KEY,
+			SequenceNo BIGINT NOT NULL
+		);`
+
+	switch dh.dialect().Name() {
+	case `sqlserver`:
+		schema := ""
+		if dh.CurrentDatabaseInfo != nil && dh.CurrentDatabaseInfo.Schema != "" {
+			schema = strings.TrimSuffix(dh.CurrentDatabaseInfo.Schema, `.`) + `.`
+		}
+		create = `IF OBJECT_ID(?, 'U') IS NULL CREATE TABLE ` + table + columns
+		cargs = []interface{}{schema + KeyGeneratorTable}
+		upsert = `MERGE INTO ` + table + ` WITH (HOLDLOCK) AS g
+			USING (SELECT ? AS SequenceName, ? AS SequenceNo) AS s ON g.SequenceName = s.SequenceName
+			WHEN MATCHED THEN UPDATE SET SequenceNo = g.SequenceNo + ?
+			WHEN NOT MATCHED THEN INSERT (SequenceName, SequenceNo) VALUES (s.SequenceName, s.SequenceNo);`
+
+	case `mysql`:
+		create = `CREATE TABLE IF NOT EXISTS ` + table + columns
+		upsert = `INSERT INTO ` + table + ` (SequenceName, SequenceNo) VALUES (?, ?) ON DUPLICATE KEY UPDATE SequenceNo = SequenceNo + ?;`
+
+	default:
+		create = `CREATE TABLE IF NOT EXISTS ` + table + columns
+		upsert = `INSERT INTO ` + table + ` (SequenceName, SequenceNo) VALUES (?, ?) ON CONFLICT (SequenceName) DO UPDATE SET SequenceNo = SequenceNo + ?;`
+	}
+
+	if err = dh.createSequenceObject(ctx, KeyGeneratorTable, create, cargs...); err != nil {
+		return 0, err
+	}
+
+	err = dh.InTransaction(ctx, nil, func(tx *DataHelper) error {
+		if _, err := tx.ExecContext(ctx, upsert, sequenceKey, n, n); err != nil {
+			return err
+		}
+
+		v, err := GetContext[int64](ctx, tx, `SELECT SequenceNo FROM `+table+` WHERE SequenceName = ?;`, sequenceKey)
+		value = v
+		return err
+	})
+
+	return value, err
+}
+
+// checkSequenceName checks that a sequence name is safe to use as part of an identifier
+func checkSequenceName(name string) error {
+
+	if name == "" || len(name) > 80 {
+		return fmt.Errorf("%w: %q", ErrInvalidSequenceName, name)
+	}
+
+	for i := 0; i < len(name); i++ {
+		if !isNameChar(name[i]) {
+			return fmt.Errorf("%w: %q", ErrInvalidSequenceName, name)
+		}
+	}
+
+	return nil
+}
+
+// bindSequenceName replaces the name placeholder of a configured sequence query with ? markers
+// and returns the sequence key as the argument of every marker. Placeholders written as
+// string literals, like '{SequenceName}' or N'{SequenceName}', lose their quotes.
+//
+// A placeholder that is only part of a name, like seq_{SequenceName}, or of a longer string,
+// like 'PFX_{SequenceName}', could not be a parameter and is an error.
+func bindSequenceName(query, placeholder, sequenceKey string) (string, []interface{}, error) {
+
+	var (
+		buf   []byte
+		args  []interface{}
+		inStr bool
+	)
+
+	at := func(i int) byte {
+		if i < 0 || i >= len(query) {
+			return 0
+		}
+		return query[i]
+	}
+
+	for i := 0; i < len(query); i++ {
+		if !strings.HasPrefix(query[i:], placeholder) {
+			if query[i] == '\'' {
+				inStr = !inStr
+			}
+			buf = append(buf, query[i])
+			continue
+		}
+
+		end := i + len(placeholder)
+		prev, next := at(i-1), at(end)
+
+		switch {
+		case inStr && prev == '\'' && at(i-2) != '\'' && next == '\'' && at(end+1) != '\'':
+			// a whole literal loses its quotes and N prefix
+			buf = buf[:len(buf)-1]
+			if n := len(buf); n > 0 && (buf[n-1] == 'N' || buf[n-1] == 'n') && (n == 1 || !isNameChar(buf[n-2])) {
+				buf = buf[:n-1]
+			}
+			inStr = false
+			i = end
+
+		case !inStr && !isIdentifierEdge(prev) && !isIdentifierEdge(next):
+			i = end - 1
+
+		default:
+			return "", nil, fmt.Errorf("%w: %s in %s", ErrSequencePlaceholder, placeholder, query)
+		}
+
+		buf = append(buf, '?')
+		args = append(args, sequenceKey)
+	}
+
+	return string(buf), args, nil
+}
+
+// isIdentifierEdge checks if a character next to a placeholder would make it part of a name
+func isIdentifierEdge(c byte) bool {
+	switch c {
+	case '.', '"', '[', ']', '`', '$', '@', '#':
+		return true
+	}
+	return isNameChar(c)
+}
+
+// sequenceBlock - numbers of a sequence reserved in the database and handed out from memory
+type sequenceBlock struct {
+	mu     sync.Mutex
+	values []int64 // reserved numbers not handed out yet
+}
+
+var (
+	blockSizeMu sync.RWMutex
+	blockSizes  = map[string]int64{}
+	blocks      sync.Map // map[string]*sequenceBlock by connection and sequence key
+)
+
+// SetSequenceBlockSize - sets a sequence key to reserve blocks of numbers. GetSequence then
+// takes size numbers of the sequence at once and hands them out from memory. A size of 1 or
+// less turns block mode off.
+//
+// Numbers reserved but not handed out before the process ends are lost, which only leaves gaps.
+// Processes sharing a sequence could use different sizes, or none. Block mode is not available
+// with configured SequenceGenerator queries. In a transaction, databases without native
+// sequences take single numbers from the key generator table instead, in the transaction.
+func SetSequenceBlockSize(sequenceKey string, size int64) {
+	blockSizeMu.Lock()
+	defer blockSizeMu.Unlock()
+
+	if size <= 1 {
+		delete(blockSizes, strings.ToLower(sequenceKey))
+		return
+	}
+	blockSizes[strings.ToLower(sequenceKey)] = size
+}
+
+// sequenceBlockSize gets the block size of a sequence key, 1 if it is not in block mode
+func sequenceBlockSize(sequenceKey string) int64 {
+	blockSizeMu.RLock()
+	defer blockSizeMu.RUnlock()
+
+	if size, ok := blockSizes[strings.ToLower(sequenceKey)]; ok {
+		return size
+	}
+	return 1
+}
+
+// blockSequence hands out the next number of the block of a sequence, reserving a new block
+// when it runs out. Blocks are reserved through the helper, so that a helper in a transaction
+// does not wait for a second connection the transaction could be locking.
+//
+// Native sequences are not rolled back, so a block reserved in a transaction stays reserved.
+// The key generator table is, so in a transaction the number is taken from the table alone,
+// the same as outside of block mode.
+func (dh *DataHelper) blockSequence(ctx context.Context, sequenceKey string, size int64) (int64, error) {
+
+	if dh.tx != nil {
+		switch dh.dialect().Name() {
+		case `postgres`, `sqlserver`:
+		default:
+			return dh.nativeSequence(ctx, sequenceKey)
+		}
+	}
+
+	bk := dh.DriverName + "\x00" + dh.connectionString + "\x00" + strings.ToLower(sequenceKey)
+	v, _ := blocks.LoadOrStore(bk, &sequenceBlock{})
+	b := v.(*sequenceBlock)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.values) == 0 {
+		values, err := dh.reserveSequence(ctx, sequenceKey, size)
+		if err != nil {
+			return 0, err
+		}
+		b.values = values
+	}
+
+	n := b.values[0]
+	b.values = b.values[1:]
+	return n, nil
+}
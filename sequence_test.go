@@ -0,0 +1,61 @@
+package datahelper
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	cfg "github.com/eaglebush/config"
+)
+
+func TestNativeSequenceDDL(t *testing.T) {
+	dh, rec := newRecorderHelper(t, `postgres`, cfg.DatabaseInfo{ParameterPlaceholder: `$`, ParameterInSequence: true})
+	ctx := context.Background()
+
+	next := `SELECT nextval($1) FROM generate_series(1, $2);`
+	steps := []struct {
+		n    int64
+		want []string
+	}{
+		{1, []string{`CREATE SEQUENCE IF NOT EXISTS seq_INVOICE START WITH 1 INCREMENT BY 1;`, next}},
+		{1, []string{next}},
+		// a block takes more values, leaving the sequence as it is
+		{10, []string{next}},
+		{1, []string{next}},
+	}
+
+	for i, s := range steps {
+		if _, err := dh.reserveSequence(ctx, `INVOICE`, s.n); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if got := rec.take(); !reflect.DeepEqual(got, s.want) {
+			t.Fatalf("step %d: unexpected queries %q", i, got)
+		}
+	}
+
+	// errors of the DDL are returned, and the DDL is tried again on the next call
+	rec.fail = `CREATE SEQUENCE`
+	if _, err := dh.nativeSequence(ctx, `ROLE`); err == nil {
+		t.Fatal("expected the CREATE error")
+	}
+	rec.fail = ``
+	rec.take()
+	if _, err := dh.nativeSequence(ctx, `ROLE`); err != nil || len(rec.take()) != 2 {
+		t.Fatalf("expected the DDL to run again, %v", err)
+	}
+
+	// DDL rolled back with a transaction runs again outside of it
+	if _, err := dh.Begin(false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dh.nativeSequence(ctx, `ORDER`); err != nil {
+		t.Fatal(err)
+	}
+	if err := dh.Rollback(false); err != nil {
+		t.Fatal(err)
+	}
+	rec.take()
+	if _, err := dh.nativeSequence(ctx, `ORDER`); err != nil || len(rec.take()) != 2 {
+		t.Fatalf("expected the DDL to run again after the transaction, %v", err)
+	}
+}
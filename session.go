@@ -0,0 +1,199 @@
+package datahelper
+
+import (
+	"database/sql"
+	"time"
+
+	cfg "github.com/eaglebush/config"
+)
+
+// Connection - a connection pool to a configured database.
+//
+// A Connection is safe for concurrent use and is meant to live as long as the application.
+// It does not run queries by itself. Each goroutine, such as an HTTP handler, gets its own
+// Session from it, which carries the transaction and error state of that unit of work.
+type Connection struct {
+	db               *sql.DB
+	registry         *Registry // registry owning the pool, which closes it on shutdown
+	connectionString string
+	info             cfg.DatabaseInfo
+	DriverName       string            // Driver name set in the configuration file
+	ConnectionID     string            // Connection ID set in the configuration file
+	Settings         cfg.Configuration // Settings from the configuration
+	Dialect          Dialect           // SQL dialect of the driver
+}
+
+// Session - a DataHelper borrowing the pool of a Connection.
+// A session must not be shared between goroutines.
+type Session struct {
+	*DataHelper
+}
+
+// Open - opens a connection pool to a database in the configuration.
+// If no connection id is specified, the default database id of the configuration is used.
+func Open(config *cfg.Configuration, ConnectionID ...string) (*Connection, error) {
+	cid := ``
+	if len(ConnectionID) > 0 {
+		cid = ConnectionID[0]
+	}
+
+	return openConnection(cid, config)
+}
+
+// Session - creates a new session on the connection
+func (c *Connection) Session() *Session {
+	dh := &DataHelper{}
+	c.attach(dh)
+
+	return &Session{DataHelper: dh}
+}
+
+// DatabaseInfo - returns a copy of the database information of the connection
+func (c *Connection) DatabaseInfo() cfg.DatabaseInfo {
+	return c.info
+}
+
+// DB - returns the underlying connection pool
+func (c *Connection) DB() *sql.DB {
+	return c.db
+}
+
+// Close - closes the connection pool. Sessions created from it could no longer run queries.
+// The pool of a connection from a Registry is shared, so it is left open until the registry is shut down.
+func (c *Connection) Close() error {
+	if c.db == nil || c.registry != nil {
+		return nil
+	}
+	return c.db.Close()
+}
+
+// Close - ends the session. An active transaction is rolled back. The connection stays open.
+func (s *Session) Close() error {
+	var err error
+	if s.tx != nil {
+		err = s.tx.Rollback()
+	}
+
+	s.tx = nil
+	s.savepoints = nil
+	s.db = nil
+
+	return err
+}
+
+// attach sets up a DataHelper to run queries on the connection
+func (c *Connection) attach(dh *DataHelper) {
+
+	// Each helper gets its own copy of the database information so that changes to it stay local
+	di := c.info
+
+	dh.db = c.db
+	dh.conn = c
+	dh.tx = nil
+	dh.savepoints = nil
+	dh.connectionString = c.connectionString
+	dh.DriverName = c.DriverName
+	dh.ConnectionID = c.ConnectionID
+	dh.Settings = c.Settings
+	dh.CurrentDatabaseInfo = &di
+	dh.Dialect = c.Dialect
+	dh.RowLimitInfo = c.Dialect.RowLimiting()
+
+	/*
+		Resets errors and assumes all queries are OK.
+		AllQueryOK is primarily used in a batch of queries.
+		This will be set to false if one of the query execution fails.
+	*/
+	dh.resetErrors()
+}
+
+// openConnection opens the pool of a database in the configuration
+func openConnection(connectid string, config *cfg.Configuration) (*Connection, error) {
+
+	c, err := newConnection(connectid, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.db, err = c.openDB(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// newConnection sets up the connection to a database in the configuration without opening its pool
+func newConnection(connectid string, config *cfg.Configuration) (*Connection, error) {
+
+	if config == nil {
+		return nil, ErrUnknownConnectionID
+	}
+
+	c := &Connection{
+		Settings:     *config,
+		ConnectionID: connectid,
+	}
+
+	if c.ConnectionID == "" && config.DefaultDatabaseID != nil {
+		c.ConnectionID = *config.DefaultDatabaseID
+	}
+
+	di := config.GetDatabaseInfo(c.ConnectionID)
+	if di == nil {
+		return nil, ErrUnknownConnectionID
+	}
+
+	// the database information is copied, so that the configuration is left as it is
+	info := *di
+
+	c.DriverName = info.DriverName
+	c.Dialect = GetDialect(c.DriverName)
+
+	// The dialect placeholder is used when the configuration leaves it empty
+	if info.ParameterPlaceholder == "" {
+		info.ParameterPlaceholder, info.ParameterInSequence = c.Dialect.Placeholder()
+	}
+
+	if len(info.ConnectionString) == 0 {
+		return nil, ErrNoConnectionString
+	}
+
+	c.connectionString = info.ConnectionString
+	c.info = info
+
+	return c, nil
+}
+
+// openDB opens the pool of the connection with the limits in its database information
+func (c *Connection) openDB() (*sql.DB, error) {
+
+	di := c.info
+
+	db, err := sql.Open(di.DriverName, di.ConnectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	if di.MaxOpenConnection != nil && *di.MaxOpenConnection != 0 {
+		db.SetMaxOpenConns(*di.MaxOpenConnection)
+	}
+
+	if di.MaxIdleConnection != nil && *di.MaxIdleConnection != 0 {
+		db.SetMaxIdleConns(*di.MaxIdleConnection)
+	}
+
+	if maxlt := di.MaxConnectionLifetime; maxlt != nil && *maxlt != 0 {
+		db.SetConnMaxLifetime(time.Hour * time.Duration(*maxlt))
+	}
+
+	if di.StorageType != "FILE" {
+		if di.Ping != nil && *di.Ping {
+			if err = db.Ping(); err != nil {
+				db.Close()
+				return nil, err
+			}
+		}
+	}
+
+	return db, nil
+}
@@ -0,0 +1,216 @@
+package datahelper
+
+import (
+	"strconv"
+	"strings"
+)
+
+// sqlTokenKind - kinds of tokens recognized in a query
+type sqlTokenKind int
+
+const (
+	tokText         sqlTokenKind = iota // SQL text that could be rewritten
+	tokString                           // single quoted or dollar quoted string literal
+	tokIdentifier                       // double quoted, bracketed or back quoted identifier
+	tokComment                          // line or block comment
+	tokParam                            // positional parameter marker ?
+	tokPlaceholder                      // schema placeholder such as {table}
+	tokNamedParam                       // named parameter such as :name
+	tokEscapedParam                     // ?? written by the caller for a literal ? operator
+)
+
+// sqlSyntax - dialect specific syntax recognized when tokenizing a query
+type sqlSyntax struct {
+	brackets bool // [name] is a quoted identifier, like in SQL Server
+	jsonOps  bool // ?| and ?& are JSON operators, like in Postgres
+}
+
+// sqlToken - a part of a query
+type sqlToken struct {
+	Kind sqlTokenKind
+	Text string // Raw text of the token. For placeholders and named parameters, this is the bare name.
+}
+
+// tokenizeSQL splits a query into tokens so that only real parameter markers and placeholders
+// are rewritten. Literals, comments and quoted identifiers are returned as is.
+// The syntax tells if [name] is read as a quoted identifier and if ?| and ?& are read as
+// operators instead of a parameter followed by | or &. A literal ? operator is written as ??.
+func tokenizeSQL(query string, syn sqlSyntax) []sqlToken {
+
+	var (
+		toks  []sqlToken
+		start int // start of the pending text
+	)
+
+	flush := func(end int) {
+		if end > start {
+			if n := len(toks); n > 0 && toks[n-1].Kind == tokText {
+				toks[n-1].Text += query[start:end]
+			} else {
+				toks = append(toks, sqlToken{Kind: tokText, Text: query[start:end]})
+			}
+		}
+	}
+
+	emit := func(i, j int, kind sqlTokenKind, text string) int {
+		flush(i)
+		toks = append(toks, sqlToken{Kind: kind, Text: text})
+		start = j
+		return j - 1
+	}
+
+	ql := len(query)
+	for i := 0; i < ql; i++ {
+		c := query[i]
+
+		switch {
+		case c == '\'':
+			j := scanQuoted(query, i+1, '\'')
+			i = emit(i, j, tokString, query[i:j])
+
+		case c == '"' || c == '`':
+			j := scanQuoted(query, i+1, c)
+			i = emit(i, j, tokIdentifier, query[i:j])
+
+		case c == '[' && syn.brackets:
+			j := scanQuoted(query, i+1, ']')
+			i = emit(i, j, tokIdentifier, query[i:j])
+
+		case c == '-' && i+1 < ql && query[i+1] == '-':
+			j := strings.IndexByte(query[i:], '\n')
+			if j == -1 {
+				j = ql
+			} else {
+				j += i
+			}
+			i = emit(i, j, tokComment, query[i:j])
+
+		case c == '/' && i+1 < ql && query[i+1] == '*':
+			j := strings.Index(query[i+2:], `*/`)
+			if j == -1 {
+				j = ql
+			} else {
+				j += i + 4
+			}
+			i = emit(i, j, tokComment, query[i:j])
+
+		case c == '$':
+			// dollar quoted strings start with $$ or $tag$. $1 is a parameter and is left as text.
+			k := i + 1
+			for k < ql && isNameChar(query[k]) && !(k == i+1 && query[k] >= '0' && query[k] <= '9') {
+				k++
+			}
+			if k >= ql || query[k] != '$' {
+				continue
+			}
+			tag := query[i : k+1]
+			j := strings.Index(query[k+1:], tag)
+			if j == -1 {
+				j = ql
+			} else {
+				j += k + 1 + len(tag)
+			}
+			i = emit(i, j, tokString, query[i:j])
+
+		case c == '?':
+			if i+1 < ql {
+				switch query[i+1] {
+				case '?':
+					i = emit(i, i+2, tokEscapedParam, `??`)
+					continue
+				case '|', '&':
+					// JSON operators ?| and ?&, but not a parameter followed by ||
+					if syn.jsonOps && !(query[i+1] == '|' && i+2 < ql && query[i+2] == '|') {
+						i++
+						continue
+					}
+				}
+			}
+			i = emit(i, i+1, tokParam, `?`)
+
+		case c == '{':
+			j := i + 1
+			for j < ql && isPlaceholderChar(query[j]) {
+				j++
+			}
+			if j < ql && query[j] == '}' {
+				i = emit(i, j+1, tokPlaceholder, query[i+1:j])
+			}
+
+		case c == ':':
+			if i+1 < ql && query[i+1] == ':' {
+				i++
+				continue
+			}
+			if i+1 < ql && isNameStart(query[i+1]) {
+				j := i + 1
+				for j < ql && isNameChar(query[j]) {
+					j++
+				}
+				i = emit(i, j, tokNamedParam, query[i+1:j])
+			}
+		}
+	}
+
+	flush(ql)
+	return toks
+}
+
+// scanQuoted returns the position after the closing quote. Doubled closing quotes are escapes.
+func scanQuoted(query string, i int, closing byte) int {
+	for ; i < len(query); i++ {
+		if query[i] != closing {
+			continue
+		}
+		if i+1 < len(query) && query[i+1] == closing {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(query)
+}
+
+func isPlaceholderChar(c byte) bool {
+	return isNameChar(c) || c == '[' || c == ']' || c == '"' || c == '-'
+}
+
+// rewriteSQL tokenizes a query and writes it back, replacing parameters and placeholders.
+// The parameter function receives the 1-based ordinal of the marker.
+// A nil function leaves the token untouched.
+func rewriteSQL(query string, syn sqlSyntax, param func(n int) string, placeholder func(name string) string) string {
+
+	var sb strings.Builder
+	sb.Grow(len(query) + 16)
+
+	n := 0
+	for _, t := range tokenizeSQL(query, syn) {
+		switch {
+		case t.Kind == tokParam && param != nil:
+			n++
+			sb.WriteString(param(n))
+		case t.Kind == tokPlaceholder && placeholder != nil:
+			sb.WriteString(placeholder(t.Text))
+		case t.Kind == tokPlaceholder:
+			sb.WriteString(`{` + t.Text + `}`)
+		case t.Kind == tokEscapedParam && param != nil:
+			sb.WriteString(`?`)
+		case t.Kind == tokNamedParam:
+			sb.WriteString(`:` + t.Text)
+		default:
+			sb.WriteString(t.Text)
+		}
+	}
+
+	return sb.String()
+}
+
+// parameterMarker returns a function that writes the nth parameter marker
+func parameterMarker(paramchar string, inSequence bool) func(n int) string {
+	return func(n int) string {
+		if inSequence {
+			return paramchar + strconv.Itoa(n)
+		}
+		return paramchar
+	}
+}
@@ -0,0 +1,59 @@
+package datahelper
+
+import (
+	"testing"
+
+	cfg "github.com/eaglebush/config"
+)
+
+func TestReplaceQueryParamMarkerSkipsLiterals(t *testing.T) {
+	dh := &DataHelper{
+		DriverName: `sqlserver`,
+		CurrentDatabaseInfo: &cfg.DatabaseInfo{
+			ParameterPlaceholder: `@p`,
+			ParameterInSequence:  true,
+		},
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`SELECT 'Is it?' AS q, [Why?] FROM t WHERE a = ?`, `SELECT 'Is it?' AS q, [Why?] FROM t WHERE a = @p1`},
+		{"SELECT a -- really?\nFROM t WHERE a = ? /* or ? */ AND b = ?", "SELECT a -- really?\nFROM t WHERE a = @p1 /* or ? */ AND b = @p2"},
+		{`SELECT 'it''s ?', "col?" FROM t WHERE a=?`, `SELECT 'it''s ?', "col?" FROM t WHERE a=@p1`},
+		// ?| and ?& are only operators in Postgres
+		{`SELECT a FROM t WHERE a = ?|4 AND b = ?&1`, `SELECT a FROM t WHERE a = @p1|4 AND b = @p2&1`},
+	}
+
+	for _, tc := range tests {
+		if got := dh.replaceQueryParamMarker(tc.query); got != tc.want {
+			t.Errorf("got %q, want %q", got, tc.want)
+		}
+	}
+
+	dh.DriverName = `postgres`
+	dh.CurrentDatabaseInfo.ParameterPlaceholder = `$`
+
+	tests = []struct {
+		query string
+		want  string
+	}{
+		{`SELECT $body$ what? $body$, $$ ? $$ FROM t WHERE a = ?`, `SELECT $body$ what? $body$, $$ ? $$ FROM t WHERE a = $1`},
+		{`SELECT data ?| array['a'], data ?& array['b'], data ?? 'c', tags[?] FROM t WHERE a = ? || 'x'`, `SELECT data ?| array['a'], data ?& array['b'], data ? 'c', tags[$1] FROM t WHERE a = $2 || 'x'`},
+	}
+
+	for _, tc := range tests {
+		if got := dh.replaceQueryParamMarker(tc.query); got != tc.want {
+			t.Errorf("got %q, want %q", got, tc.want)
+		}
+	}
+}
+
+func TestReplaceCustomPlaceHolderSkipsLiterals(t *testing.T) {
+	got := replaceCustomPlaceHolder(`SELECT '{literal}' FROM {table1} a INNER JOIN {[tran_123-a]} b ON a.k = b.k -- {comment}`, `sch`)
+	want := `SELECT '{literal}' FROM sch.table1 a INNER JOIN sch.[tran_123-a] b ON a.k = b.k -- {comment}`
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,126 @@
+package datahelper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/eaglebush/datatable"
+)
+
+// TableDef - metadata of a table for the record reader
+type TableDef struct {
+	Name        string      // Table name, could be a {table} placeholder
+	KeyColumn   string      // Surrogate key column, read by READBYKEY
+	CodeColumn  string      // Business code column, read by READBYCODE
+	Columns     []string    // Columns read by READALL, READBYKEY and READBYCODE. All columns if empty.
+	FormColumns []string    // Reduced columns read by READFORFORM for lookups
+	OrderBy     []string    // Ordering of READALL and READFORFORM, such as "Code" or "DateCreated DESC"
+	IDGenerator IDGenerator // Generator of the KeyColumn values of new rows, used by Insert
+}
+
+var (
+	tableDefMu sync.RWMutex
+	tableDefs  = map[string]TableDef{}
+)
+
+// RegisterTable - registers a table definition by its name. A registered definition replaces the previous one.
+func RegisterTable(def TableDef) {
+	tableDefMu.Lock()
+	defer tableDefMu.Unlock()
+
+	tableDefs[tableDefKey(def.Name)] = def
+}
+
+// GetTable - gets the definition registered for a table name. The braces of a {table} placeholder are optional.
+func GetTable(name string) (TableDef, bool) {
+	tableDefMu.RLock()
+	defer tableDefMu.RUnlock()
+
+	def, ok := tableDefs[tableDefKey(name)]
+	return def, ok
+}
+
+func tableDefKey(name string) string {
+	return strings.ToLower(strings.Trim(name, `{}`))
+}
+
+// Read - reads the records of a table with a read type:
+//
+//	READALL     - all rows
+//	READBYKEY   - the row with the surrogate key in value
+//	READBYCODE  - the row with the business code in value
+//	READFORFORM - the form columns of all rows, or of the row with the key in value if it is not nil
+func (dh *DataHelper) Read(def TableDef, readType ReadType, value interface{}) (*datatable.DataTable, error) {
+	return dh.ReadContext(context.Background(), def, readType, value)
+}
+
+// ReadContext - reads the records of a table with a read type and a context
+func (dh *DataHelper) ReadContext(ctx context.Context, def TableDef, readType ReadType, value interface{}) (*datatable.DataTable, error) {
+
+	q, err := dh.readQuery(def, readType, value)
+	if err != nil {
+		return datatable.NewDataTable("data"), err
+	}
+
+	return q.GetDataContext(ctx)
+}
+
+// ReadTable - reads the records of a registered table with a read type
+func (dh *DataHelper) ReadTable(name string, readType ReadType, value interface{}) (*datatable.DataTable, error) {
+	return dh.ReadTableContext(context.Background(), name, readType, value)
+}
+
+// ReadTableContext - reads the records of a registered table with a read type and a context
+func (dh *DataHelper) ReadTableContext(ctx context.Context, name string, readType ReadType, value interface{}) (*datatable.DataTable, error) {
+
+	def, ok := GetTable(name)
+	if !ok {
+		return datatable.NewDataTable("data"), fmt.Errorf("%w: %s", ErrUnknownTable, name)
+	}
+
+	return dh.ReadContext(ctx, def, readType, value)
+}
+
+// readQuery builds the query of a read type
+func (dh *DataHelper) readQuery(def TableDef, readType ReadType, value interface{}) (*SelectBuilder, error) {
+
+	if def.Name == "" {
+		return nil, ErrNoTableName
+	}
+
+	switch readType {
+	case READALL:
+		return dh.Select(def.Columns...).From(def.Name).OrderBy(def.OrderBy...), nil
+
+	case READBYKEY:
+		if def.KeyColumn == "" {
+			return nil, fmt.Errorf("%w: %s has no key column", ErrNoColumn, def.Name)
+		}
+		return dh.Select(def.Columns...).From(def.Name).Where(dh.quoteName(def.KeyColumn)+` = ?`, value), nil
+
+	case READBYCODE:
+		if def.CodeColumn == "" {
+			return nil, fmt.Errorf("%w: %s has no code column", ErrNoColumn, def.Name)
+		}
+		return dh.Select(def.Columns...).From(def.Name).Where(dh.quoteName(def.CodeColumn)+` = ?`, value), nil
+
+	case READFORFORM:
+		if len(def.FormColumns) == 0 {
+			return nil, fmt.Errorf("%w: %s has no form columns", ErrNoColumn, def.Name)
+		}
+
+		sb := dh.Select(def.FormColumns...).From(def.Name)
+		if value == nil {
+			return sb.OrderBy(def.OrderBy...), nil
+		}
+
+		if def.KeyColumn == "" {
+			return nil, fmt.Errorf("%w: %s has no key column", ErrNoColumn, def.Name)
+		}
+		return sb.Where(dh.quoteName(def.KeyColumn)+` = ?`, value), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnknownReadType, readType)
+}
@@ -0,0 +1,136 @@
+package datahelper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// TxFunc - a unit of work run by InTransaction
+type TxFunc func(tx *DataHelper) error
+
+// InTransaction - runs a function in a transaction.
+//
+// The transaction is committed when the function returns nil, and rolled back when it returns
+// an error or panics. A panic is raised again after the rollback. If the DataHelper is already
+// in a transaction, the function runs within a save point of it instead, and the options are ignored.
+func (dh *DataHelper) InTransaction(ctx context.Context, opts *sql.TxOptions, fn TxFunc) (err error) {
+
+	if dh.IsInTransaction() {
+		return dh.inSavepoint(ctx, fn)
+	}
+
+	if _, err = dh.BeginContext(ctx, opts, false); err != nil {
+		return err
+	}
+
+	// A transaction is done after commit or rollback even if they fail
+	defer func() {
+		p := recover()
+		if p != nil {
+			dh.Rollback(false)
+		}
+		dh.tx = nil
+		dh.savepoints = nil
+		if p != nil {
+			panic(p)
+		}
+	}()
+
+	if err = fn(dh); err != nil {
+		if rerr := dh.Rollback(false); rerr != nil {
+			return errors.Join(err, rerr)
+		}
+		return err
+	}
+
+	return dh.Commit(false)
+}
+
+// inSavepoint runs a function inside a save point of the current transaction
+func (dh *DataHelper) inSavepoint(ctx context.Context, fn TxFunc) (err error) {
+
+	pid := `dh_sp` + strconv.Itoa(len(dh.savepoints)+1)
+	if err = dh.MarkContext(ctx, pid); err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			dh.DiscardContext(ctx, pid)
+			dh.ReleaseContext(ctx, pid)
+			panic(p)
+		}
+	}()
+
+	if err = fn(dh); err != nil {
+		if rerr := dh.DiscardContext(ctx, pid); rerr != nil {
+			return errors.Join(err, rerr)
+		}
+		if rerr := dh.ReleaseContext(ctx, pid); rerr != nil {
+			return errors.Join(err, rerr)
+		}
+		return err
+	}
+
+	return dh.ReleaseContext(ctx, pid)
+}
+
+// RetryOptions - options of RetryTransaction
+type RetryOptions struct {
+	MaxAttempts int              // Maximum number of runs including the first. Default is 3.
+	BaseDelay   time.Duration    // Delay before the second run, doubled for every run after. Default is 50ms.
+	MaxDelay    time.Duration    // Maximum delay between runs. Default is 2s.
+	Retryable   func(error) bool // Checks if a failed run should be retried. Default is IsRetryable.
+}
+
+// RetryTransaction - runs a function in a transaction, running it again in a new transaction
+// when it fails with a deadlock or a serialization failure. Runs are spaced with a jittered
+// exponential backoff. It returns the number of runs it took.
+//
+// If the DataHelper is already in a transaction, the function is run once since the outer
+// transaction could not be retried from here.
+func (dh *DataHelper) RetryTransaction(ctx context.Context, opts *sql.TxOptions, ropts RetryOptions, fn TxFunc) (attempts int, err error) {
+
+	if dh.IsInTransaction() {
+		return 1, dh.InTransaction(ctx, opts, fn)
+	}
+
+	if ropts.MaxAttempts <= 0 {
+		ropts.MaxAttempts = 3
+	}
+	if ropts.BaseDelay <= 0 {
+		ropts.BaseDelay = 50 * time.Millisecond
+	}
+	if ropts.MaxDelay <= 0 {
+		ropts.MaxDelay = 2 * time.Second
+	}
+	if ropts.Retryable == nil {
+		ropts.Retryable = IsRetryable
+	}
+
+	delay := ropts.BaseDelay
+	for attempts = 1; ; attempts++ {
+
+		if err = dh.InTransaction(ctx, opts, fn); err == nil || attempts >= ropts.MaxAttempts || !ropts.Retryable(err) {
+			return attempts, err
+		}
+
+		// wait between half and the full delay
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempts, errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+
+		if delay *= 2; delay > ropts.MaxDelay {
+			delay = ropts.MaxDelay
+		}
+	}
+}
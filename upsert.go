@@ -0,0 +1,127 @@
+package datahelper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// UpsertResult - what an upsert did to the row
+type UpsertResult int
+
+// Constants
+const (
+	UpsertUnknown  UpsertResult = 0 // The database could not tell if the row was inserted or updated
+	UpsertInserted UpsertResult = 1 // A new row was inserted
+	UpsertUpdated  UpsertResult = 2 // A row with the same key existed and was updated or kept
+)
+
+// String - name of the result
+func (r UpsertResult) String() string {
+	switch r {
+	case UpsertInserted:
+		return `inserted`
+	case UpsertUpdated:
+		return `updated`
+	}
+	return `unknown`
+}
+
+// Upsert - inserts a row, or updates the row with the same key columns, using the native
+// statement of the database. The values could be a map with string keys or a struct, and
+// must include the key columns. Struct fields are written like in Insert.
+//
+// Postgres, SQL Server and MySQL report if the row was inserted or updated. SQLite could not
+// tell and returns UpsertUnknown.
+func (dh *DataHelper) Upsert(table string, keyColumns []string, values interface{}) (UpsertResult, error) {
+	return dh.UpsertContext(context.Background(), table, keyColumns, values)
+}
+
+// UpsertContext - inserts or updates a row with a context
+func (dh *DataHelper) UpsertContext(ctx context.Context, table string, keyColumns []string, values interface{}) (UpsertResult, error) {
+
+	if table == "" {
+		return UpsertUnknown, ErrNoTableName
+	}
+
+	if len(keyColumns) == 0 {
+		return UpsertUnknown, ErrNoColumn
+	}
+
+	cols, vals, err := columnValues(values)
+	if err != nil {
+		return UpsertUnknown, err
+	}
+
+	for _, k := range keyColumns {
+		found := false
+		for _, c := range cols {
+			if strings.EqualFold(c, k) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return UpsertUnknown, fmt.Errorf("%w: key column %s has no value", ErrNoColumn, k)
+		}
+	}
+
+	qkeys := make([]string, len(keyColumns))
+	for i, k := range keyColumns {
+		qkeys[i] = dh.quoteName(k)
+	}
+
+	qcols := make([]string, len(cols))
+	for i, c := range cols {
+		qcols[i] = dh.quoteName(c)
+	}
+
+	d := dh.dialect()
+	query := d.Upsert(dh.quoteName(table), qkeys, qcols)
+
+	switch d.Name() {
+	case `postgres`:
+		// xmax is only zero on a freshly inserted row. A conflict with DO NOTHING returns no row.
+		dt, err := dh.GetDataContext(ctx, query+` RETURNING (xmax = 0) AS inserted`, vals...)
+		if err != nil {
+			return UpsertUnknown, err
+		}
+		if dt.RowCount > 0 {
+			if ins, ok := dt.Rows[0].Cells[0].Value.(bool); ok && ins {
+				return UpsertInserted, nil
+			}
+		}
+		return UpsertUpdated, nil
+
+	case `sqlserver`:
+		// a MERGE without a matched clause outputs no row on a match
+		dt, err := dh.GetDataContext(ctx, strings.TrimSuffix(query, `;`)+` OUTPUT $action;`, vals...)
+		if err != nil {
+			return UpsertUnknown, err
+		}
+		if dt.RowCount > 0 && strings.EqualFold(fmt.Sprint(dt.Rows[0].Cells[0].Value), `INSERT`) {
+			return UpsertInserted, nil
+		}
+		return UpsertUpdated, nil
+
+	case `mysql`:
+		// MySQL counts an inserted row as 1, an updated row as 2 and an unchanged row as 0
+		res, err := dh.ExecContext(ctx, query, vals...)
+		if err != nil {
+			return UpsertUnknown, err
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			if n == 1 {
+				return UpsertInserted, nil
+			}
+			return UpsertUpdated, nil
+		}
+		return UpsertUnknown, nil
+	}
+
+	if _, err = dh.ExecContext(ctx, query, vals...); err != nil {
+		return UpsertUnknown, err
+	}
+
+	return UpsertUnknown, nil
+}
@@ -0,0 +1,203 @@
+package datahelper
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Insert - inserts a row into a table. The values could be a map with string keys or a struct.
+//
+// Struct fields are written to the column of their db tag or their name. Fields tagged
+// db:"-" or with the readonly option are skipped.
+//
+// If the table is registered with an IDGenerator, a key column that is missing or zero is filled
+// with a new ID, which is also set to the key field of a struct passed by pointer.
+func (dh *DataHelper) Insert(table string, values interface{}) (sql.Result, error) {
+	return dh.InsertContext(context.Background(), table, values)
+}
+
+// InsertContext - inserts a row into a table with a context
+func (dh *DataHelper) InsertContext(ctx context.Context, table string, values interface{}) (sql.Result, error) {
+
+	cols, vals, err := columnValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	if def, ok := GetTable(table); ok && def.IDGenerator != nil && def.KeyColumn != "" {
+		if cols, vals, err = dh.generateKey(ctx, def, values, cols, vals); err != nil {
+			return nil, err
+		}
+	}
+
+	return dh.InsertInto(table).Columns(cols...).Values(vals...).ExecContext(ctx)
+}
+
+// generateKey fills the key column of a table with a new ID when it is missing or zero
+func (dh *DataHelper) generateKey(ctx context.Context, def TableDef, values interface{}, cols []string, vals []interface{}) ([]string, []interface{}, error) {
+
+	ki := -1
+	for i, c := range cols {
+		if strings.EqualFold(c, def.KeyColumn) {
+			ki = i
+			break
+		}
+	}
+
+	if ki != -1 && vals[ki] != nil && !reflect.ValueOf(vals[ki]).IsZero() {
+		return cols, vals, nil
+	}
+
+	id, err := def.IDGenerator.NextID(ctx, dh)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if ki == -1 {
+		cols = append(cols, def.KeyColumn)
+		vals = append(vals, id)
+	} else {
+		vals[ki] = id
+	}
+
+	// the new ID is set to the key field of a struct passed by pointer
+	rv := reflect.ValueOf(values)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return cols, vals, nil
+	}
+	rv = rv.Elem()
+
+	for _, f := range structFields(rv.Type()) {
+		if !strings.EqualFold(f.Name, def.KeyColumn) {
+			continue
+		}
+
+		fv, err := rv.FieldByIndexErr(f.Index)
+		if err != nil || !fv.CanSet() {
+			break
+		}
+
+		iv := reflect.ValueOf(id)
+		if fv.Kind() == reflect.Pointer && idConvertible(iv.Type(), fv.Type().Elem()) {
+			p := reflect.New(fv.Type().Elem())
+			p.Elem().Set(iv.Convert(fv.Type().Elem()))
+			fv.Set(p)
+		} else if idConvertible(iv.Type(), fv.Type()) {
+			fv.Set(iv.Convert(fv.Type()))
+		}
+		break
+	}
+
+	return cols, vals, nil
+}
+
+// idConvertible checks that an ID converts to a field type without numbers turning into runes
+func idConvertible(from, to reflect.Type) bool {
+	if to.Kind() == reflect.String && from.Kind() != reflect.String {
+		return false
+	}
+	return from.ConvertibleTo(to)
+}
+
+// Update - updates the rows of a table that match the where condition. The values could be a
+// map with string keys or a struct, and are written like in Insert. An empty condition updates all rows.
+func (dh *DataHelper) Update(table string, values interface{}, where string, args ...interface{}) (sql.Result, error) {
+	return dh.UpdateContext(context.Background(), table, values, where, args...)
+}
+
+// UpdateContext - updates the rows of a table that match the where condition with a context
+func (dh *DataHelper) UpdateContext(ctx context.Context, table string, values interface{}, where string, args ...interface{}) (sql.Result, error) {
+
+	cols, vals, err := columnValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	ub := dh.UpdateTable(table)
+	for i, c := range cols {
+		ub.Set(c, vals[i])
+	}
+
+	if where != "" {
+		ub.Where(where, args...)
+	}
+
+	return ub.ExecContext(ctx)
+}
+
+// Delete - deletes the rows of a table that match the where condition. An empty condition deletes all rows.
+func (dh *DataHelper) Delete(table string, where string, args ...interface{}) (sql.Result, error) {
+	return dh.DeleteContext(context.Background(), table, where, args...)
+}
+
+// DeleteContext - deletes the rows of a table that match the where condition with a context
+func (dh *DataHelper) DeleteContext(ctx context.Context, table string, where string, args ...interface{}) (sql.Result, error) {
+
+	db := dh.DeleteFrom(table)
+	if where != "" {
+		db.Where(where, args...)
+	}
+
+	return db.ExecContext(ctx)
+}
+
+// columnValues returns the columns and values to write from a map or a struct.
+// Map keys are sorted so that the same map always makes the same statement.
+func columnValues(values interface{}) ([]string, []interface{}, error) {
+
+	rv := reflect.ValueOf(values)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil, fmt.Errorf("Values must not be a nil %T", values)
+		}
+		rv = rv.Elem()
+	}
+
+	var (
+		cols []string
+		vals []interface{}
+	)
+
+	switch {
+	case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String:
+		keys := make([]string, 0, rv.Len())
+		for _, k := range rv.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			cols = append(cols, k)
+			vals = append(vals, rv.MapIndex(reflect.ValueOf(k).Convert(rv.Type().Key())).Interface())
+		}
+
+	case rv.Kind() == reflect.Struct:
+		for _, f := range structFields(rv.Type()) {
+			if f.ReadOnly {
+				continue
+			}
+
+			// fields of a nil embedded struct pointer are written as NULL
+			var v interface{}
+			if fv, err := rv.FieldByIndexErr(f.Index); err == nil {
+				v = fv.Interface()
+			}
+
+			cols = append(cols, f.Name)
+			vals = append(vals, v)
+		}
+
+	default:
+		return nil, nil, fmt.Errorf("Values must be a map with string keys or a struct, not %T", values)
+	}
+
+	if len(cols) == 0 {
+		return nil, nil, ErrNoColumn
+	}
+
+	return cols, vals, nil
+}